@@ -51,6 +51,19 @@ type GatekeeperSpec struct {
 	// +optional
 	Webhook *WebhookConfig `json:"webhook,omitempty"`
 
+	// Namespace Gatekeeper is deployed to. Defaults to the namespace the
+	// operator is configured with. Changing it migrates Gatekeeper to the new
+	// namespace, switching the webhooks over once Gatekeeper is ready there.
+	// +kubebuilder:validation:MaxLength:=63
+	// +kubebuilder:validation:Pattern:=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Namespace"
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Namespace Configuration"
+	// +optional
+	NamespaceConfig *NamespaceConfig `json:"namespaceConfig,omitempty"`
+
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Node Selector"
 	// +optional
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
@@ -66,6 +79,52 @@ type GatekeeperSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Pod Annotations"
 	// +optional
 	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// PodLabels are added to the pods of the audit and webhook deployments.
+	// The labels selected by the deployments cannot be overridden.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Pod Labels"
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// CommonLabels are added to all the resources managed by the operator.
+	// The labels of the Gatekeeper manifests take precedence.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Common Labels"
+	// +optional
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+
+	// CommonAnnotations are added to all the resources managed by the
+	// operator. The annotations of the Gatekeeper manifests take precedence.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Common Annotations"
+	// +optional
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Monitoring"
+	// +optional
+	Monitoring *MonitoringConfig `json:"monitoring,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Metrics"
+	// +optional
+	Metrics *MetricsConfig `json:"metrics,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Profiling"
+	// +optional
+	Profiling *ProfilingConfig `json:"profiling,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Mutating CRD Deletion"
+	// +optional
+	MutatingCRDDeletion *MutatingCRDDeletionPolicy `json:"mutatingCRDDeletion,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Deletion Policy"
+	// +optional
+	DeletionPolicy *DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Pruning"
+	// +optional
+	Pruning *PruningMode `json:"pruning,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Backup Storage"
+	// +optional
+	BackupStorage *BackupStorage `json:"backupStorage,omitempty"`
 }
 
 type ImageConfig struct {
@@ -80,8 +139,31 @@ type ImageConfig struct {
 	Image *string `json:"image,omitempty"`
 	// +optional
 	ImagePullPolicy *corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// ImageRegistry replaces the registry of the Gatekeeper image, e.g. with
+	// a mirror in disconnected clusters. It may include a path, e.g.
+	// mirror.example.com:5000/gatekeeper.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9.-]+(:[0-9]+)?(/[a-z0-9._-]+)*$`
+	// +optional
+	ImageRegistry *string `json:"imageRegistry,omitempty"`
+	// ImagePullSecrets are the Secrets in the Gatekeeper namespace used to
+	// pull the Gatekeeper image.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// DigestPolicy is whether the Gatekeeper images must be pinned to a
+	// digest. With Required, images referenced by tag only are rejected.
+	// Defaults to Optional.
+	// +optional
+	DigestPolicy *ImageDigestPolicy `json:"digestPolicy,omitempty"`
 }
 
+// +kubebuilder:validation:Enum:=Required;Optional
+type ImageDigestPolicy string
+
+const (
+	ImageDigestRequired ImageDigestPolicy = "Required"
+	ImageDigestOptional ImageDigestPolicy = "Optional"
+)
+
 type AuditConfig struct {
 	// +kubebuilder:validation:Minimum:=0
 	// +optional
@@ -102,6 +184,14 @@ type AuditConfig struct {
 	EmitAuditEvents *EmitEventsMode `json:"emitAuditEvents,omitempty"`
 	// +optional
 	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// PodLabels are added to the audit pods, taking precedence over the
+	// shared pod labels.
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+	// Image of the audit pods, overriding the Gatekeeper image e.g. to canary
+	// a patched image. It is used as is, without the image registry override.
+	// +optional
+	Image *string `json:"image,omitempty"`
 }
 
 // +kubebuilder:validation:Enum:=Enabled;Disabled
@@ -128,8 +218,384 @@ type WebhookConfig struct {
 	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
 	// +optional
 	DisabledBuiltins []string `json:"disabledBuiltins,omitempty"`
+	// ClientConfig configures how the API server reaches the Gatekeeper
+	// webhook.
+	// +optional
+	ClientConfig *WebhookClientConfig `json:"clientConfig,omitempty"`
+	// HostNetwork runs the webhook pods in the host's network namespace. This
+	// is needed on clusters where the control plane cannot reach pod IPs.
+	// +optional
+	HostNetwork *bool `json:"hostNetwork,omitempty"`
+	// DNSPolicy of the webhook pods. Defaults to ClusterFirstWithHostNet when
+	// hostNetwork is enabled.
+	// +optional
+	DNSPolicy *corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+	// Port the webhook server listens on. Defaults to 8443.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=65535
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+	// HealthPort the webhook health and readiness probes are served on.
+	// Defaults to 9090.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=65535
+	// +optional
+	HealthPort *int32 `json:"healthPort,omitempty"`
+	// MetricsPort the webhook Prometheus metrics are served on. Defaults to
+	// 8888.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=65535
+	// +optional
+	MetricsPort *int32 `json:"metricsPort,omitempty"`
+	// PodLabels are added to the webhook pods, taking precedence over the
+	// shared pod labels.
+	// +optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+	// Image of the webhook pods, overriding the Gatekeeper image. It is used
+	// as is, without the image registry override.
+	// +optional
+	Image *string `json:"image,omitempty"`
+	// Service configures the webhook Service.
+	// +optional
+	Service *ServiceConfig `json:"service,omitempty"`
+	// SLO configures the service level objectives of the webhook evaluated
+	// by the operator from the metrics of the webhook pods.
+	// +optional
+	SLO *WebhookSLOConfig `json:"slo,omitempty"`
+	// CircuitBreaker configures the operator to set the failure policy of
+	// the webhooks to Ignore while the readiness of the webhook deployment
+	// is below a threshold after it was healthy. The circuit breaker is
+	// disabled if not set.
+	// +optional
+	CircuitBreaker *WebhookCircuitBreakerConfig `json:"circuitBreaker,omitempty"`
+	// PolicyReadyGate configures the operator to keep the failure policy of
+	// the webhooks at Ignore after install or upgrade until all constraint
+	// templates and constraints are enforced on all webhook pods. The gate is
+	// disabled if not set.
+	// +optional
+	PolicyReadyGate *WebhookPolicyReadyGateConfig `json:"policyReadyGate,omitempty"`
+	// Prober configures the operator to periodically probe the webhooks with
+	// dry-run admission requests through the API server. The prober is
+	// disabled if not set.
+	// +optional
+	Prober *WebhookProberConfig `json:"prober,omitempty"`
+}
+
+type WebhookCircuitBreakerConfig struct {
+	// ReadyPercentThreshold is the percentage of ready webhook replicas
+	// below which the circuit breaker opens. Defaults to 50.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=100
+	// +optional
+	ReadyPercentThreshold *int32 `json:"readyPercentThreshold,omitempty"`
+	// SoakPeriod is how long the readiness must stay at or above the
+	// threshold before the circuit breaker closes and the configured failure
+	// policy is restored. Defaults to 5m.
+	// +optional
+	SoakPeriod *metav1.Duration `json:"soakPeriod,omitempty"`
+}
+
+type WebhookPolicyReadyGateConfig struct {
+	// Timeout after which the webhook is reported as Degraded if the
+	// policies are still not enforced on all webhook pods. The failure policy
+	// is kept at Ignore until they are. Defaults to 10m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type WebhookProberConfig struct {
+	// Interval between the probes. Defaults to 1m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Timeout of each probe. Defaults to 5s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type WebhookSLOConfig struct {
+	// LatencyP99Threshold is the maximum 99th percentile latency of the
+	// admission requests handled by the webhook.
+	// +optional
+	LatencyP99Threshold *metav1.Duration `json:"latencyP99Threshold,omitempty"`
+	// ErrorRatioThreshold is the maximum ratio of the admission requests
+	// failing with an error, between 0 and 1 e.g. "0.05".
+	// +kubebuilder:validation:Pattern:=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	// +optional
+	ErrorRatioThreshold *string `json:"errorRatioThreshold,omitempty"`
+	// Interval between the evaluations of the objectives. Defaults to 1m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+type ServiceConfig struct {
+	// Annotations to add to the Service.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Labels to add to the Service.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// InternalTrafficPolicy of the Service.
+	// +optional
+	InternalTrafficPolicy *corev1.ServiceInternalTrafficPolicyType `json:"internalTrafficPolicy,omitempty"`
+	// IPFamilies of the Service.
+	// +optional
+	IPFamilies []corev1.IPFamily `json:"ipFamilies,omitempty"`
+	// IPFamilyPolicy of the Service. Must be set to PreferDualStack or
+	// RequireDualStack when more than one IP family is configured.
+	// +optional
+	IPFamilyPolicy *corev1.IPFamilyPolicy `json:"ipFamilyPolicy,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=Service;URL
+type WebhookClientConfigMode string
+
+const (
+	WebhookClientConfigService WebhookClientConfigMode = "Service"
+	WebhookClientConfigURL     WebhookClientConfigMode = "URL"
+)
+
+// +kubebuilder:validation:Enum:=None;LoadBalancer;Route
+type WebhookExposeMode string
+
+const (
+	WebhookExposeNone         WebhookExposeMode = "None"
+	WebhookExposeLoadBalancer WebhookExposeMode = "LoadBalancer"
+	WebhookExposeRoute        WebhookExposeMode = "Route"
+)
+
+type WebhookClientConfig struct {
+	// Mode selects whether the webhook configurations reference the in-cluster
+	// webhook Service (the default) or an externally reachable URL. The URL
+	// mode is intended for hosted control planes where the API server cannot
+	// reach in-cluster Services.
+	// +optional
+	Mode *WebhookClientConfigMode `json:"mode,omitempty"`
+	// URL configures the externally reachable endpoint used when mode is URL.
+	// +optional
+	URL *WebhookURLConfig `json:"url,omitempty"`
+}
+
+type WebhookURLConfig struct {
+	// Host is the externally reachable host name of the webhook. It is added
+	// to the webhook serving certificate's subject alternative names.
+	// +kubebuilder:validation:MinLength:=1
+	Host string `json:"host"`
+	// Port is the externally reachable port of the webhook. Defaults to 443.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=65535
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+	// Expose selects how the operator exposes the webhook at the given host.
+	// None expects the endpoint to be provisioned externally e.g. by an
+	// ingress, LoadBalancer provisions a LoadBalancer Service and Route
+	// provisions an OpenShift passthrough Route. Defaults to None.
+	// +optional
+	Expose *WebhookExposeMode `json:"expose,omitempty"`
 }
 
+// +kubebuilder:validation:Enum:=Enabled;Disabled
+type MonitoringMode string
+
+const (
+	MonitoringEnabled  MonitoringMode = "Enabled"
+	MonitoringDisabled MonitoringMode = "Disabled"
+)
+
+// MonitoringConfig configures the integration with the prometheus-operator.
+// The prometheus-operator resources are only created if the
+// monitoring.coreos.com API group is available when the operator starts.
+type MonitoringConfig struct {
+	// ServiceMonitor creates a ServiceMonitor scraping the metrics of the
+	// audit and webhook pods. On OpenShift, the Gatekeeper namespace is also
+	// labeled for cluster monitoring. Defaults to Disabled.
+	// +optional
+	ServiceMonitor *MonitoringMode `json:"serviceMonitor,omitempty"`
+	// PrometheusRule creates a PrometheusRule with alerts on the webhook
+	// error rate and latency, the audit duration, the webhook certificate
+	// expiry and constraint template ingestion errors. The alerts are
+	// versioned with the Gatekeeper manifests. Defaults to Disabled.
+	// +optional
+	PrometheusRule *MonitoringMode `json:"prometheusRule,omitempty"`
+	// Dashboards creates ConfigMaps containing Grafana dashboards for the
+	// Gatekeeper metrics, labeled to be discovered by the Grafana dashboard
+	// sidecar. Defaults to Disabled.
+	// +optional
+	Dashboards *MonitoringMode `json:"dashboards,omitempty"`
+	// Interval at which metrics are scraped. Defaults to 30s.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Labels to add to the monitoring resources e.g. to match the selectors
+	// of a Prometheus instance.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// NamespaceConfig configures the metadata of the Gatekeeper namespace.
+type NamespaceConfig struct {
+	// PodSecurity configures the Pod Security Admission labels of the
+	// namespace. The labels of the Gatekeeper manifests, enforcing the
+	// restricted level, are kept for unset levels and versions.
+	// +optional
+	PodSecurity *PodSecurityConfig `json:"podSecurity,omitempty"`
+	// Labels to add to the namespace e.g. to match the namespace selectors
+	// of a Prometheus instance. The admission.gatekeeper.sh/ignore and
+	// gatekeeper.sh/system labels cannot be overridden.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations to add to the namespace.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PodSecurityConfig configures the Pod Security Admission levels and versions
+// of the Gatekeeper namespace.
+type PodSecurityConfig struct {
+	// +optional
+	Enforce *PodSecurityLevel `json:"enforce,omitempty"`
+	// +kubebuilder:validation:Pattern:=`^(latest|v[0-9]+\.[0-9]+)$`
+	// +optional
+	EnforceVersion *string `json:"enforceVersion,omitempty"`
+	// +optional
+	Audit *PodSecurityLevel `json:"audit,omitempty"`
+	// +kubebuilder:validation:Pattern:=`^(latest|v[0-9]+\.[0-9]+)$`
+	// +optional
+	AuditVersion *string `json:"auditVersion,omitempty"`
+	// +optional
+	Warn *PodSecurityLevel `json:"warn,omitempty"`
+	// +kubebuilder:validation:Pattern:=`^(latest|v[0-9]+\.[0-9]+)$`
+	// +optional
+	WarnVersion *string `json:"warnVersion,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=privileged;baseline;restricted
+type PodSecurityLevel string
+
+const (
+	PodSecurityPrivileged PodSecurityLevel = "privileged"
+	PodSecurityBaseline   PodSecurityLevel = "baseline"
+	PodSecurityRestricted PodSecurityLevel = "restricted"
+)
+
+// +kubebuilder:validation:Enum:=prometheus;stackdriver;opencensus;opentelemetry
+type MetricsBackend string
+
+const (
+	MetricsBackendPrometheus    MetricsBackend = "prometheus"
+	MetricsBackendStackdriver   MetricsBackend = "stackdriver"
+	MetricsBackendOpenCensus    MetricsBackend = "opencensus"
+	MetricsBackendOpenTelemetry MetricsBackend = "opentelemetry"
+)
+
+// MetricsConfig configures the metrics exported by the audit and webhook.
+type MetricsConfig struct {
+	// Backends the metrics are exported to. Defaults to prometheus.
+	// +listType=set
+	// +optional
+	Backends []MetricsBackend `json:"backends,omitempty"`
+	// PrometheusPort the audit and webhook Prometheus metrics are served on.
+	// The webhook metricsPort takes precedence for the webhook. Defaults to
+	// 8888.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=65535
+	// +optional
+	PrometheusPort *int32 `json:"prometheusPort,omitempty"`
+	// OTLP configures the export of metrics with the opentelemetry backend,
+	// which requires a Gatekeeper version supporting it. The opentelemetry
+	// backend must be listed in the backends.
+	// +optional
+	OTLP *OTLPConfig `json:"otlp,omitempty"`
+}
+
+type OTLPConfig struct {
+	// Endpoint is the host:port of the OTLP receiver.
+	// +kubebuilder:validation:MinLength:=1
+	Endpoint string `json:"endpoint"`
+	// Insecure disables TLS for the connection to the receiver. Defaults to
+	// false.
+	// +optional
+	Insecure *bool `json:"insecure,omitempty"`
+}
+
+// +kubebuilder:validation:Enum:=Enabled;Disabled
+type ProfilingMode string
+
+const (
+	ProfilingEnabled  ProfilingMode = "Enabled"
+	ProfilingDisabled ProfilingMode = "Disabled"
+)
+
+// ProfilingConfig configures the Go pprof endpoint of the audit and webhook.
+// Profiles can then be captured with the
+// operator.gatekeeper.sh/capture-profile annotation on the Gatekeeper
+// resource.
+type ProfilingConfig struct {
+	// Pprof enables the pprof endpoint. Defaults to Disabled.
+	// +optional
+	Pprof *ProfilingMode `json:"pprof,omitempty"`
+	// Port the pprof endpoint is served on. The endpoint only listens on
+	// localhost. Defaults to 6060.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=65535
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+}
+
+// MutatingCRDDeletionPolicy is how the mutating CRDs are deleted when the
+// mutating webhook is disabled, since deleting them deletes all the Assign and
+// AssignMetadata mutators. Refuse keeps the CRDs while mutators exist. Backup
+// backs up the mutators into the mutators archive in the Gatekeeper namespace
+// before deleting the CRDs. Force deletes the CRDs regardless. Defaults to
+// Refuse.
+// +kubebuilder:validation:Enum:=Refuse;Backup;Force
+type MutatingCRDDeletionPolicy string
+
+const (
+	MutatingCRDDeletionRefuse MutatingCRDDeletionPolicy = "Refuse"
+	MutatingCRDDeletionBackup MutatingCRDDeletionPolicy = "Backup"
+	MutatingCRDDeletionForce  MutatingCRDDeletionPolicy = "Force"
+)
+
+// DeletionPolicy is what the operator removes when the Gatekeeper resource is
+// deleted. Retain leaves Gatekeeper running and its CRDs and policies in
+// place. RemoveWorkloadsOnly removes the webhook configurations and the
+// Gatekeeper workloads but keeps the CRDs and thus the constraint templates,
+// constraints and mutators. Purge removes everything including the CRDs and
+// the policy archives.
+// Defaults to RemoveWorkloadsOnly.
+// +kubebuilder:validation:Enum:=Retain;RemoveWorkloadsOnly;Purge
+type DeletionPolicy string
+
+const (
+	DeletionPolicyRetain              DeletionPolicy = "Retain"
+	DeletionPolicyRemoveWorkloadsOnly DeletionPolicy = "RemoveWorkloadsOnly"
+	DeletionPolicyPurge               DeletionPolicy = "Purge"
+)
+
+// BackupStorage is the kind of the resources the policy archives are written
+// to. Secret keeps the archived policies out of reach of the users that may
+// only read ConfigMaps. Archives are read from either kind. Defaults to
+// Secret.
+// +kubebuilder:validation:Enum:=Secret;ConfigMap
+type BackupStorage string
+
+const (
+	BackupStorageSecret    BackupStorage = "Secret"
+	BackupStorageConfigMap BackupStorage = "ConfigMap"
+)
+
+// PruningMode is whether the resources labeled with the inventory of the
+// Gatekeeper resource that are no longer part of the Gatekeeper manifests,
+// e.g. after an operator upgrade, are deleted. DryRun only reports them in
+// the status. Defaults to DryRun.
+// +kubebuilder:validation:Enum:=Enabled;DryRun;Disabled
+type PruningMode string
+
+const (
+	PruningEnabled  PruningMode = "Enabled"
+	PruningDryRun   PruningMode = "DryRun"
+	PruningDisabled PruningMode = "Disabled"
+)
+
 // +kubebuilder:validation:Enum:=DEBUG;INFO;WARNING;ERROR
 type LogLevelMode string
 
@@ -165,11 +631,78 @@ type GatekeeperStatus struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Observed Generation"
 	ObservedGeneration int64 `json:"observedGeneration"`
 
+	// Namespace Gatekeeper is deployed to. It differs from spec.namespace
+	// while Gatekeeper is migrated to a new namespace.
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Namespace"
+	Namespace string `json:"namespace,omitempty"`
+
 	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Audit Conditions"
 	AuditConditions []StatusCondition `json:"auditConditions"`
 
 	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Webhook Conditions"
 	WebhookConditions []StatusCondition `json:"webhookConditions"`
+
+	// WebhookProbes are the results of the last synthetic probe of each
+	// webhook.
+	// +listType=map
+	// +listMapKey=webhook
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Webhook Probes"
+	WebhookProbes []WebhookProbeStatus `json:"webhookProbes,omitempty"`
+
+	// BlockedMutators is the number of mutators blocking the deletion of the
+	// mutating CRDs while the mutating webhook is disabled.
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Blocked Mutators"
+	BlockedMutators int32 `json:"blockedMutators,omitempty"`
+
+	// PruneCandidates are the resources that are no longer part of the
+	// Gatekeeper manifests and would be pruned if pruning was enabled, and
+	// the obsolete CRDs, which are never pruned.
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Prune Candidates"
+	PruneCandidates []string `json:"pruneCandidates,omitempty"`
+
+	// Images running in the audit and webhook pods as of the last
+	// reconciliation.
+	// +listType=map
+	// +listMapKey=pod
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status,displayName="Images"
+	Images []PodImageStatus `json:"images,omitempty"`
+}
+
+// PodImageStatus is the image running in a Gatekeeper pod.
+type PodImageStatus struct {
+	// Pod is the name of the pod.
+	Pod string `json:"pod"`
+	// Component of Gatekeeper run by the pod, either audit or webhook.
+	Component string `json:"component"`
+	// Image of the pod.
+	Image string `json:"image"`
+	// ImageID is the image digest reported by the container runtime, or
+	// empty until the image is pulled.
+	// +optional
+	ImageID string `json:"imageID,omitempty"`
+}
+
+// WebhookProbeStatus is the result of the last synthetic probe of a webhook.
+type WebhookProbeStatus struct {
+	// Webhook is the name of the probed webhook.
+	Webhook string `json:"webhook"`
+	// Succeeded is whether the webhook answered the probe within the
+	// timeout.
+	Succeeded bool `json:"succeeded"`
+	// Latency of the probe.
+	// +optional
+	Latency metav1.Duration `json:"latency,omitempty"`
+	// LastProbeTime is when the webhook was last probed.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+	// Message describing the failure of the probe.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // StatusCondition describes the current state of a component.
@@ -192,12 +725,13 @@ type StatusCondition struct {
 	Message string `json:"message,omitempty"`
 }
 
-// +kubebuilder:validation:Enum:=Ready;Not Ready
+// +kubebuilder:validation:Enum:=Ready;Not Ready;Degraded
 type StatusConditionType string
 
 const (
 	StatusReady    StatusConditionType = "Ready"
 	StatusNotReady StatusConditionType = "Not Ready"
+	StatusDegraded StatusConditionType = "Degraded"
 )
 
 // +kubebuilder:object:root=true
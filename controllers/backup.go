@@ -0,0 +1,546 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	// BackupAnnotation requests a backup of the Gatekeeper policy resources
+	// into the archive named by its value.
+	BackupAnnotation = "operator.gatekeeper.sh/backup"
+	// RestoreAnnotation requests the restore of the Gatekeeper policy
+	// resources from the archive named by its value.
+	RestoreAnnotation = "operator.gatekeeper.sh/restore"
+	// ArchiveLabel is set to the archive name on the Secrets or ConfigMaps
+	// containing the chunks of an archive.
+	ArchiveLabel             = "operator.gatekeeper.sh/archive"
+	ArchiveChunkAnnotation   = "operator.gatekeeper.sh/archive-chunk"
+	ArchiveVersionAnnotation = "operator.gatekeeper.sh/archive-version"
+	BackedUpAtAnnotation     = "operator.gatekeeper.sh/backed-up-at"
+
+	policyArchiveVersion = 1
+	policyArchiveKey     = "archive.json.gz"
+	restoreRetryInterval = 10 * time.Second
+	restoreTimeout       = 5 * time.Minute
+)
+
+// archiveChunkSize is the size of the archive chunks. Secrets and ConfigMaps
+// are limited to 1MiB, leave room for the metadata.
+var archiveChunkSize = 900 * 1024
+
+// policyCRDs are the CRDs of the Gatekeeper policy resources, in the order
+// they are restored. The constraints are restored last since Gatekeeper only
+// creates their CRDs once their constraint templates are restored.
+var policyCRDs = []string{
+	ConfigCRDFile,
+	ProviderCRDFile,
+	ConstraintTemplateCRDFile,
+	ExpansionTemplateCRDFile,
+	AssignCRDFile,
+	AssignMetadataCRDFile,
+	ModifySetCRDFile,
+}
+
+// policyArchive is the versioned archive of Gatekeeper policy resources.
+type policyArchive struct {
+	Version int `json:"version"`
+	// Namespace is the Gatekeeper namespace the archive was created from.
+	// Namespaced resources are restored into the current Gatekeeper
+	// namespace.
+	Namespace string                      `json:"namespace"`
+	CreatedAt metav1.Time                 `json:"createdAt"`
+	Items     []unstructured.Unstructured `json:"items"`
+}
+
+// archiveStorages are the kinds of the resources archives may be stored in.
+var archiveStorages = []operatorv1alpha1.BackupStorage{
+	operatorv1alpha1.BackupStorageSecret,
+	operatorv1alpha1.BackupStorageConfigMap,
+}
+
+func backupStorage(gatekeeper *operatorv1alpha1.Gatekeeper) operatorv1alpha1.BackupStorage {
+	if gatekeeper.Spec.BackupStorage != nil {
+		return *gatekeeper.Spec.BackupStorage
+	}
+	return operatorv1alpha1.BackupStorageSecret
+}
+
+func archiveChunkName(name string, chunk int) string {
+	return fmt.Sprintf("gatekeeper-backup-%s-%d", name, chunk)
+}
+
+func validateArchiveName(name string) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid archive name %q: %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// handlePolicyArchives performs the backup and restore requested by the
+// annotations of the Gatekeeper resource and removes the annotations once
+// done. It returns whether the restore is waiting for the CRDs of some
+// resources to be served, in which case the restore annotation is kept and
+// the restore retried until the restore timeout.
+func (r *GatekeeperReconciler) handlePolicyArchives(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper) (bool, error) {
+	if name, ok := gatekeeper.GetAnnotations()[BackupAnnotation]; ok {
+		count, err := r.backupPolicies(ctx, gatekeeper, name)
+		if err != nil {
+			r.Log.Error(err, "Unable to back up policies", "archive", name)
+			r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonBackupFailed, "Unable to back up policies into archive %s: %v", name, err)
+		} else {
+			r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonBackedUp, "Backed up %d policy resources into archive %s", count, name)
+		}
+		if err := r.removeAnnotation(ctx, gatekeeper, BackupAnnotation); err != nil {
+			return false, err
+		}
+	}
+
+	name, ok := gatekeeper.GetAnnotations()[RestoreAnnotation]
+	if !ok {
+		r.restoreSince = time.Time{}
+		return false, nil
+	}
+	if r.restoreSince.IsZero() {
+		r.restoreSince = time.Now()
+	}
+	restored, pending, err := r.restorePolicies(ctx, gatekeeper, name)
+	switch {
+	case err != nil:
+		r.Log.Error(err, "Unable to restore policies", "archive", name)
+		r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonRestoreFailed, "Unable to restore policies from archive %s: %v", name, err)
+	case pending > 0 && time.Since(r.restoreSince) < restoreTimeout:
+		r.Log.Info("Waiting for the CRDs of the policy resources to restore", "archive", name, "restored", restored, "pending", pending)
+		return true, nil
+	case pending > 0:
+		r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonRestoreFailed,
+			"Restored %d policy resources from archive %s, %d could not be restored since their kind is not served after %s", restored, name, pending, restoreTimeout)
+	default:
+		r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonRestored, "Restored %d policy resources from archive %s", restored, name)
+	}
+	r.restoreSince = time.Time{}
+	return false, r.removeAnnotation(ctx, gatekeeper, RestoreAnnotation)
+}
+
+// listPolicies returns the Gatekeeper policy resources in restore order.
+// Resources whose CRD does not exist and the probe mutator are skipped.
+func (r *GatekeeperReconciler) listPolicies(ctx context.Context) ([]unstructured.Unstructured, error) {
+	var policies, templates []unstructured.Unstructured
+	for _, asset := range policyCRDs {
+		gvk, err := crdListGVK(asset)
+		if err != nil {
+			return nil, err
+		}
+		items, err := r.listUnstructured(ctx, gvk, "")
+		if err != nil {
+			if meta.IsNoMatchError(errors.Cause(err)) || apierrors.IsNotFound(errors.Cause(err)) {
+				continue
+			}
+			return nil, err
+		}
+		items = withoutProbeObjects(items)
+		if asset == ConstraintTemplateCRDFile {
+			templates = items
+		}
+		policies = append(policies, items...)
+	}
+	for _, template := range templates {
+		kind, _, _ := unstructured.NestedString(template.Object, "spec", "crd", "spec", "names", "kind")
+		constraints := &unstructured.UnstructuredList{}
+		constraints.SetAPIVersion(constraintsGroupVersion)
+		constraints.SetKind(kind + "List")
+		if err := r.List(ctx, constraints); err != nil {
+			if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "Unable to list %s constraints", kind)
+		}
+		policies = append(policies, constraints.Items...)
+	}
+	return policies, nil
+}
+
+// backupPolicies backs up the Gatekeeper policy resources into the archive of
+// the given name and returns the number of resources backed up.
+func (r *GatekeeperReconciler) backupPolicies(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, name string) (int, error) {
+	if err := validateArchiveName(name); err != nil {
+		return 0, err
+	}
+	policies, err := r.listPolicies(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.writePolicyArchive(ctx, gatekeeper, name, policies); err != nil {
+		return 0, err
+	}
+	return len(policies), nil
+}
+
+// writePolicyArchive writes the given resources as a gzipped JSON archive
+// split into chunks across the Secrets or ConfigMaps of the archive of the
+// given name, depending on the backup storage, and deletes the chunks of a
+// previous archive of the same name that are no longer needed. The chunks are
+// not owned by the Gatekeeper resource so that they outlive it.
+func (r *GatekeeperReconciler) writePolicyArchive(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, name string, items []unstructured.Unstructured) error {
+	archive := policyArchive{
+		Version:   policyArchiveVersion,
+		Namespace: r.gatekeeperNamespace(),
+		CreatedAt: metav1.Now(),
+		Items:     make([]unstructured.Unstructured, 0, len(items)),
+	}
+	for _, item := range items {
+		item := item.DeepCopy()
+		unstructured.RemoveNestedField(item.Object, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(item.Object, "metadata", "generation")
+		unstructured.RemoveNestedField(item.Object, "metadata", "managedFields")
+		unstructured.RemoveNestedField(item.Object, "metadata", "ownerReferences")
+		unstructured.RemoveNestedField(item.Object, "metadata", "resourceVersion")
+		unstructured.RemoveNestedField(item.Object, "metadata", "uid")
+		unstructured.RemoveNestedField(item.Object, "status")
+		archive.Items = append(archive.Items, *item)
+	}
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return errors.Wrap(err, "Unable to marshal archive")
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return errors.Wrap(err, "Unable to compress archive")
+	}
+	if err := zw.Close(); err != nil {
+		return errors.Wrap(err, "Unable to compress archive")
+	}
+
+	storage := backupStorage(gatekeeper)
+	compressed := buf.Bytes()
+	chunks := (len(compressed) + archiveChunkSize - 1) / archiveChunkSize
+	backedUpAt := archive.CreatedAt.UTC().Format(time.RFC3339)
+	written := map[string]bool{}
+	for i := 0; i < chunks; i++ {
+		end := (i + 1) * archiveChunkSize
+		if end > len(compressed) {
+			end = len(compressed)
+		}
+		chunk := newArchiveChunk(storage, r.gatekeeperNamespace(), archiveChunkName(name, i), name)
+		chunk.SetAnnotations(map[string]string{
+			ArchiveChunkAnnotation:   fmt.Sprintf("%d/%d", i, chunks),
+			ArchiveVersionAnnotation: strconv.Itoa(policyArchiveVersion),
+			BackedUpAtAnnotation:     backedUpAt,
+		})
+		if err := setArchiveChunkData(chunk, compressed[i*archiveChunkSize:end]); err != nil {
+			return err
+		}
+		if err := r.writeArchiveChunk(ctx, chunk); err != nil {
+			return err
+		}
+		written[string(storage)+"/"+chunk.GetName()] = true
+	}
+
+	// The chunks of a previous archive stored in the other kind are stale too.
+	for _, kind := range archiveStorages {
+		stale, err := r.listArchiveChunks(ctx, r.gatekeeperNamespace(), kind, name)
+		if err != nil {
+			return err
+		}
+		for i := range stale {
+			if written[string(kind)+"/"+stale[i].GetName()] {
+				continue
+			}
+			if err := r.Delete(ctx, &stale[i]); err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "Unable to delete stale archive chunk %s %s/%s", kind, stale[i].GetNamespace(), stale[i].GetName())
+			}
+		}
+	}
+	r.Log.Info("Wrote policy archive", "archive", name, "storage", storage, "resources", len(archive.Items), "chunks", chunks)
+	return nil
+}
+
+// newArchiveChunk returns an empty chunk of the archive of the given name,
+// stored in a resource of the given kind.
+func newArchiveChunk(storage operatorv1alpha1.BackupStorage, namespace, chunkName, archiveName string) *unstructured.Unstructured {
+	chunk := &unstructured.Unstructured{}
+	chunk.SetAPIVersion("v1")
+	chunk.SetKind(string(storage))
+	chunk.SetNamespace(namespace)
+	chunk.SetName(chunkName)
+	chunk.SetLabels(map[string]string{ArchiveLabel: archiveName})
+	if storage == operatorv1alpha1.BackupStorageSecret {
+		chunk.Object["type"] = string(corev1.SecretTypeOpaque)
+	}
+	return chunk
+}
+
+// archiveDataField returns the field holding the binary data of a resource
+// of the kind of the given chunk.
+func archiveDataField(chunk *unstructured.Unstructured) string {
+	if chunk.GetKind() == string(operatorv1alpha1.BackupStorageSecret) {
+		return "data"
+	}
+	return "binaryData"
+}
+
+func setArchiveChunkData(chunk *unstructured.Unstructured, data []byte) error {
+	if err := unstructured.SetNestedField(chunk.Object, map[string]interface{}{
+		policyArchiveKey: base64.StdEncoding.EncodeToString(data),
+	}, archiveDataField(chunk)); err != nil {
+		return errors.Wrap(err, "Unable to set archive data")
+	}
+	return nil
+}
+
+func archiveChunkData(chunk *unstructured.Unstructured) ([]byte, error) {
+	encoded, _, _ := unstructured.NestedString(chunk.Object, archiveDataField(chunk), policyArchiveKey)
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// writeArchiveChunk creates or replaces the given archive chunk.
+func (r *GatekeeperReconciler) writeArchiveChunk(ctx context.Context, chunk *unstructured.Unstructured) error {
+	namespacedName := types.NamespacedName{Namespace: chunk.GetNamespace(), Name: chunk.GetName()}
+	clusterObj := &unstructured.Unstructured{}
+	clusterObj.SetGroupVersionKind(chunk.GroupVersionKind())
+	err := r.Get(ctx, namespacedName, clusterObj)
+	switch {
+	case err == nil:
+		chunk.SetResourceVersion(clusterObj.GetResourceVersion())
+		err = r.Update(ctx, chunk)
+	case apierrors.IsNotFound(err):
+		err = r.Create(ctx, chunk)
+	default:
+		return errors.Wrapf(err, "Error attempting to get resource %s", namespacedName)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Unable to write archive chunk %s %s", chunk.GetKind(), namespacedName)
+	}
+	return nil
+}
+
+// listArchiveChunks returns the chunks stored in resources of the given kind
+// in the given namespace of the archive of the given name, or of every
+// archive if the name is empty.
+func (r *GatekeeperReconciler) listArchiveChunks(ctx context.Context, namespace string, storage operatorv1alpha1.BackupStorage, name string) ([]unstructured.Unstructured, error) {
+	chunks := &unstructured.UnstructuredList{}
+	chunks.SetAPIVersion("v1")
+	chunks.SetKind(string(storage) + "List")
+	opts := []client.ListOption{client.InNamespace(namespace), client.HasLabels{ArchiveLabel}}
+	if name != "" {
+		opts = []client.ListOption{client.InNamespace(namespace), client.MatchingLabels{ArchiveLabel: name}}
+	}
+	if err := r.List(ctx, chunks, opts...); err != nil {
+		if name == "" {
+			return nil, errors.Wrapf(err, "Unable to list the archive %ss in namespace %s", storage, namespace)
+		}
+		return nil, errors.Wrapf(err, "Unable to list the chunks of archive %s", name)
+	}
+	return chunks.Items, nil
+}
+
+// readPolicyArchive reads and checks the archive of the given name. The
+// archive is read from the backup storage, or else from the other kind so
+// that the archives written before the backup storage was changed can still
+// be restored.
+func (r *GatekeeperReconciler) readPolicyArchive(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, name string) (*policyArchive, error) {
+	var chunks []unstructured.Unstructured
+	storage := backupStorage(gatekeeper)
+	for _, kind := range append([]operatorv1alpha1.BackupStorage{storage}, archiveStorages...) {
+		var err error
+		chunks, err = r.listArchiveChunks(ctx, r.gatekeeperNamespace(), kind, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) > 0 {
+			break
+		}
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("archive %s not found in namespace %s", name, r.gatekeeperNamespace())
+	}
+
+	data := make([][]byte, len(chunks))
+	for i := range chunks {
+		var index, count int
+		chunk := chunks[i].GetAnnotations()[ArchiveChunkAnnotation]
+		if _, err := fmt.Sscanf(chunk, "%d/%d", &index, &count); err != nil {
+			return nil, fmt.Errorf("invalid chunk %q of archive %s in %s %s", chunk, name, chunks[i].GetKind(), chunks[i].GetName())
+		}
+		if count != len(chunks) || index < 0 || index >= count {
+			return nil, fmt.Errorf("archive %s is incomplete, found %d of %d chunks", name, len(chunks), count)
+		}
+		decoded, err := archiveChunkData(&chunks[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to decode chunk %d of archive %s", index, name)
+		}
+		data[index] = decoded
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(bytes.Join(data, nil)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to decompress archive %s", name)
+	}
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to decompress archive %s", name)
+	}
+	archive := &policyArchive{}
+	if err := json.Unmarshal(decompressed, archive); err != nil {
+		return nil, errors.Wrapf(err, "Unable to unmarshal archive %s", name)
+	}
+	if archive.Version != policyArchiveVersion {
+		return nil, fmt.Errorf("unsupported version %d of archive %s", archive.Version, name)
+	}
+	return archive, nil
+}
+
+// moveArchives moves the archives stored in the given namespace Gatekeeper is
+// migrated from to the current Gatekeeper namespace, where they replace the
+// archives of the same name.
+func (r *GatekeeperReconciler) moveArchives(ctx context.Context, source string) error {
+	var names []string
+	sourceChunks := map[string][]unstructured.Unstructured{}
+	for _, kind := range archiveStorages {
+		chunks, err := r.listArchiveChunks(ctx, source, kind, "")
+		if err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			name := chunk.GetLabels()[ArchiveLabel]
+			if _, ok := sourceChunks[name]; !ok {
+				names = append(names, name)
+			}
+			sourceChunks[name] = append(sourceChunks[name], chunk)
+		}
+	}
+
+	for _, name := range names {
+		for _, kind := range archiveStorages {
+			chunks, err := r.listArchiveChunks(ctx, r.gatekeeperNamespace(), kind, name)
+			if err != nil {
+				return err
+			}
+			if err := r.deleteArchiveChunks(ctx, chunks); err != nil {
+				return err
+			}
+		}
+		for _, chunk := range sourceChunks[name] {
+			moved := newArchiveChunk(operatorv1alpha1.BackupStorage(chunk.GetKind()), r.gatekeeperNamespace(), chunk.GetName(), name)
+			moved.SetAnnotations(chunk.GetAnnotations())
+			data, err := archiveChunkData(&chunk)
+			if err != nil {
+				return errors.Wrapf(err, "Unable to decode archive chunk %s %s/%s", chunk.GetKind(), source, chunk.GetName())
+			}
+			if err := setArchiveChunkData(moved, data); err != nil {
+				return err
+			}
+			if err := r.writeArchiveChunk(ctx, moved); err != nil {
+				return err
+			}
+		}
+		if err := r.deleteArchiveChunks(ctx, sourceChunks[name]); err != nil {
+			return err
+		}
+		r.Log.Info("Moved policy archive", "archive", name, "from", source, "to", r.gatekeeperNamespace())
+	}
+	return nil
+}
+
+// deleteArchives deletes every archive stored in the given namespace.
+func (r *GatekeeperReconciler) deleteArchives(ctx context.Context, namespace string) error {
+	for _, kind := range archiveStorages {
+		chunks, err := r.listArchiveChunks(ctx, namespace, kind, "")
+		if err != nil {
+			return err
+		}
+		if err := r.deleteArchiveChunks(ctx, chunks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GatekeeperReconciler) deleteArchiveChunks(ctx context.Context, chunks []unstructured.Unstructured) error {
+	for i := range chunks {
+		if err := r.Delete(ctx, &chunks[i]); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "Unable to delete archive chunk %s %s/%s", chunks[i].GetKind(), chunks[i].GetNamespace(), chunks[i].GetName())
+		}
+	}
+	return nil
+}
+
+// restorePolicies creates or updates the resources of the archive of the
+// given name, in archive order. It returns the number of restored resources,
+// and of resources pending since their kind is not served e.g. constraints
+// whose CRD Gatekeeper has not created yet from the restored constraint
+// template.
+func (r *GatekeeperReconciler) restorePolicies(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, name string) (int, int, error) {
+	if err := validateArchiveName(name); err != nil {
+		return 0, 0, err
+	}
+	archive, err := r.readPolicyArchive(ctx, gatekeeper, name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	restored, pending := 0, 0
+	for i := range archive.Items {
+		item := &archive.Items[i]
+		if item.GetNamespace() != "" && item.GetNamespace() == archive.Namespace {
+			item.SetNamespace(r.gatekeeperNamespace())
+		}
+		namespacedName := types.NamespacedName{Namespace: item.GetNamespace(), Name: item.GetName()}
+		clusterObj := &unstructured.Unstructured{}
+		clusterObj.SetGroupVersionKind(item.GroupVersionKind())
+		err := r.Get(ctx, namespacedName, clusterObj)
+		switch {
+		case meta.IsNoMatchError(err):
+			pending++
+			continue
+		case err == nil:
+			item.SetResourceVersion(clusterObj.GetResourceVersion())
+			err = r.Update(ctx, item)
+		case apierrors.IsNotFound(err):
+			err = r.Create(ctx, item)
+		}
+		if err != nil {
+			return restored, pending, errors.Wrapf(err, "Unable to restore %s %s", item.GetKind(), namespacedName)
+		}
+		restored++
+	}
+	r.Log.Info("Restored policy archive", "archive", name, "restored", restored, "pending", pending)
+	return restored, pending, nil
+}
@@ -0,0 +1,164 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+func TestValidateArchiveName(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(validateArchiveName("nightly")).To(Succeed())
+	g.Expect(validateArchiveName("")).ToNot(Succeed())
+	g.Expect(validateArchiveName("Nightly")).ToNot(Succeed())
+	g.Expect(validateArchiveName("nightly/1")).ToNot(Succeed())
+}
+
+func TestPolicyArchive(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+	gvks := []schema.GroupVersionKind{constraintListGVK}
+	for _, asset := range policyCRDs {
+		gvk, err := crdListGVK(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		gvks = append(gvks, gvk)
+	}
+	for _, gvk := range gvks {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.UnstructuredList{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind[:len(gvk.Kind)-len("List")]), &unstructured.Unstructured{})
+	}
+	configListGVK, err := crdListGVK(ConfigCRDFile)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	template := policyObject(constraintTemplateListGVK, "", "k8srequiredlabels", "template-uid", map[string]interface{}{"created": true})
+	g.Expect(unstructured.SetNestedField(template.Object, "K8sRequiredLabels", "spec", "crd", "spec", "names", "kind")).To(Succeed())
+	constraint := policyObject(constraintListGVK, "", "ns-must-have-owner", "constraint-uid", nil)
+	config := policyObject(configListGVK, namespace, "config", "config-uid", nil)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(template, constraint, config).Build()
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace}
+	gatekeeper := &operatorv1alpha1.Gatekeeper{ObjectMeta: metav1.ObjectMeta{Name: defaultGatekeeperCrName}}
+
+	// test backup in chunks, stored in Secrets by default
+	defer func(size int) { archiveChunkSize = size }(archiveChunkSize)
+	archiveChunkSize = 64
+	g.Expect(r.backupPolicies(ctx, gatekeeper, "nightly")).To(Equal(3))
+	chunks, err := r.listArchiveChunks(ctx, namespace, operatorv1alpha1.BackupStorageSecret, "nightly")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(len(chunks)).To(BeNumerically(">", 1))
+	for _, chunk := range chunks {
+		g.Expect(chunk.Object).To(HaveKeyWithValue("type", "Opaque"))
+		g.Expect(chunk.Object).To(HaveKey("data"))
+		g.Expect(chunk.GetAnnotations()).To(HaveKeyWithValue(ArchiveVersionAnnotation, "1"))
+		g.Expect(chunk.GetAnnotations()).To(HaveKey(BackedUpAtAnnotation))
+		g.Expect(chunk.GetOwnerReferences()).To(BeEmpty())
+	}
+	archive, err := r.readPolicyArchive(ctx, gatekeeper, "nightly")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(archive.Namespace).To(Equal(namespace))
+	g.Expect(archive.Items).To(HaveLen(3))
+	g.Expect(archive.Items[0].GetKind()).To(Equal("Config"))
+	g.Expect(archive.Items[1].GetKind()).To(Equal("ConstraintTemplate"))
+	g.Expect(archive.Items[1].Object).ToNot(HaveKey("status"))
+	g.Expect(archive.Items[1].GetUID()).To(BeEmpty())
+	g.Expect(archive.Items[2].GetKind()).To(Equal("K8sRequiredLabels"))
+
+	// test the Secret chunks are replaced by ConfigMap chunks
+	storage := operatorv1alpha1.BackupStorageConfigMap
+	gatekeeper.Spec.BackupStorage = &storage
+	archiveChunkSize = 900 * 1024
+	g.Expect(r.backupPolicies(ctx, gatekeeper, "nightly")).To(Equal(3))
+	chunks, err = r.listArchiveChunks(ctx, namespace, operatorv1alpha1.BackupStorageSecret, "nightly")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(chunks).To(BeEmpty())
+	chunks, err = r.listArchiveChunks(ctx, namespace, operatorv1alpha1.BackupStorageConfigMap, "nightly")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(chunks).To(HaveLen(1))
+	g.Expect(chunks[0].GetName()).To(Equal(archiveChunkName("nightly", 0)))
+	g.Expect(chunks[0].Object).To(HaveKey("binaryData"))
+
+	// test an archive stored in the other kind is read
+	gatekeeper.Spec.BackupStorage = nil
+	archive, err = r.readPolicyArchive(ctx, gatekeeper, "nightly")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(archive.Items).To(HaveLen(3))
+
+	// test restore into a new namespace, waiting for the constraint CRD
+	movedNamespace := "moved-gatekeeper-system"
+	chunk := chunks[0].DeepCopy()
+	chunk.SetNamespace(movedNamespace)
+	chunk.SetResourceVersion("")
+	gatekeeper = &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+			Annotations: map[string]string{
+				RestoreAnnotation: "nightly",
+			},
+		},
+	}
+	constraintCRDServed := false
+	c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(gatekeeper, chunk).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			if gvk.Group == constraintListGVK.Group && !constraintCRDServed {
+				return &meta.NoKindMatchError{GroupKind: gvk.GroupKind()}
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+	recorder := record.NewFakeRecorder(10)
+	r = &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: movedNamespace, Recorder: recorder}
+
+	g.Expect(r.handlePolicyArchives(ctx, gatekeeper)).To(BeTrue())
+	g.Expect(gatekeeper.GetAnnotations()).To(HaveKey(RestoreAnnotation))
+	g.Expect(recorder.Events).To(BeEmpty())
+	restoredConfig := &unstructured.Unstructured{}
+	restoredConfig.SetGroupVersionKind(config.GroupVersionKind())
+	g.Expect(c.Get(ctx, types.NamespacedName{Namespace: movedNamespace, Name: "config"}, restoredConfig)).To(Succeed())
+
+	constraintCRDServed = true
+	g.Expect(r.handlePolicyArchives(ctx, gatekeeper)).To(BeFalse())
+	g.Expect(recorder.Events).To(Receive(Equal("Normal Restored Restored 3 policy resources from archive nightly")))
+	g.Expect(gatekeeper.GetAnnotations()).ToNot(HaveKey(RestoreAnnotation))
+	g.Expect(r.restoreSince.IsZero()).To(BeTrue())
+	restoredConstraint := &unstructured.Unstructured{}
+	restoredConstraint.SetGroupVersionKind(constraint.GroupVersionKind())
+	g.Expect(c.Get(ctx, types.NamespacedName{Name: "ns-must-have-owner"}, restoredConstraint)).To(Succeed())
+
+	// test restore of a missing archive
+	gatekeeper.SetAnnotations(map[string]string{RestoreAnnotation: "weekly"})
+	g.Expect(r.handlePolicyArchives(ctx, gatekeeper)).To(BeFalse())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning RestoreFailed Unable to restore policies from archive weekly")))
+	g.Expect(gatekeeper.GetAnnotations()).ToNot(HaveKey(RestoreAnnotation))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	CACertName = "ca.crt"
+	CAKeyName  = "ca.key"
+	CertName   = "tls.crt"
+	KeyName    = "tls.key"
+
+	certValidityDuration = 10 * 365 * 24 * time.Hour
+	// Regenerate the certificates when they are this close to expiring.
+	certLookaheadInterval = 90 * 24 * time.Hour
+	certOrganization      = "gatekeeper"
+)
+
+// webhookCertHosts returns the host names and IP addresses the webhook
+// serving certificate must be valid for. The in-cluster Service names are
+// always included so that switching between clientConfig modes does not
+// require a new certificate.
+func webhookCertHosts(namespace string, hosts ...string) []string {
+	certHosts := []string{
+		fmt.Sprintf("%s.%s.svc", WebhookServiceName, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", WebhookServiceName, namespace),
+	}
+	for _, h := range hosts {
+		if h != "" {
+			certHosts = append(certHosts, h)
+		}
+	}
+	return certHosts
+}
+
+// generateWebhookCertificates creates a self-signed CA and a serving
+// certificate signed by it that is valid for the given hosts. IP addresses
+// are set as IP subject alternative names since the API server does not match
+// them against DNS names. The returned map is keyed by the Secret data keys
+// expected by Gatekeeper.
+func generateWebhookCertificates(hosts []string, now time.Time) (map[string][]byte, error) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate CA private key")
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName:   "gatekeeper-ca",
+			Organization: []string{certOrganization},
+		},
+		NotBefore:             now.Add(-1 * time.Hour),
+		NotAfter:              now.Add(certValidityDuration),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create CA certificate")
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse CA certificate")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate serving private key")
+	}
+	var dnsNames []string
+	var ipAddresses []net.IP
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{
+			CommonName:   hosts[0],
+			Organization: []string{certOrganization},
+		},
+		DNSNames:    dnsNames,
+		IPAddresses: ipAddresses,
+		NotBefore:   now.Add(-1 * time.Hour),
+		NotAfter:    now.Add(certValidityDuration),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create serving certificate")
+	}
+
+	return map[string][]byte{
+		CACertName: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		CAKeyName:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(caKey)}),
+		CertName:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyName:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}, nil
+}
+
+// webhookCertificatesValid returns true if the given Secret data contains a
+// serving certificate signed by the contained CA that is valid for all of the
+// given hosts and is not about to expire.
+func webhookCertificatesValid(data map[string][]byte, hosts []string, now time.Time) bool {
+	for _, k := range []string{CACertName, CertName, KeyName} {
+		if len(data[k]) == 0 {
+			return false
+		}
+	}
+
+	caBlock, _ := pem.Decode(data[CACertName])
+	if caBlock == nil {
+		return false
+	}
+	caCert, err := x509.ParseCertificate(caBlock.Bytes)
+	if err != nil {
+		return false
+	}
+	certBlock, _ := pem.Decode(data[CertName])
+	if certBlock == nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return false
+	}
+	keyBlock, _ := pem.Decode(data[KeyName])
+	if keyBlock == nil {
+		return false
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return false
+	}
+	certKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok || !key.PublicKey.Equal(certKey) {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	lookahead := now.Add(certLookaheadInterval)
+	for _, host := range hosts {
+		// The host is matched against the IP subject alternative names if it
+		// is an IP address.
+		opts := x509.VerifyOptions{
+			DNSName:     host,
+			Roots:       roots,
+			CurrentTime: lookahead,
+		}
+		if _, err := cert.Verify(opts); err != nil {
+			return false
+		}
+	}
+	return true
+}
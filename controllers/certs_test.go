@@ -0,0 +1,76 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestWebhookCertificates(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Now()
+	hosts := webhookCertHosts(namespace, "gatekeeper.example.com")
+	g.Expect(hosts).To(ConsistOf(
+		"gatekeeper-webhook-service.mygatekeeper.svc",
+		"gatekeeper-webhook-service.mygatekeeper.svc.cluster.local",
+		"gatekeeper.example.com",
+	))
+
+	// test missing certificates
+	g.Expect(webhookCertificatesValid(nil, hosts, now)).To(BeFalse())
+
+	// test generated certificates
+	data, err := generateWebhookCertificates(hosts, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(HaveKey(CACertName))
+	g.Expect(data).To(HaveKey(CAKeyName))
+	g.Expect(data).To(HaveKey(CertName))
+	g.Expect(data).To(HaveKey(KeyName))
+	g.Expect(webhookCertificatesValid(data, hosts, now)).To(BeTrue())
+
+	// test certificates are invalid for a different host
+	otherHosts := webhookCertHosts(namespace, "other.example.com")
+	g.Expect(webhookCertificatesValid(data, otherHosts, now)).To(BeFalse())
+
+	// test certificates that are about to expire
+	g.Expect(webhookCertificatesValid(data, hosts, now.Add(certValidityDuration-certLookaheadInterval/2))).To(BeFalse())
+
+	// test mismatched private key
+	otherData, err := generateWebhookCertificates(hosts, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	data[KeyName] = otherData[KeyName]
+	g.Expect(webhookCertificatesValid(data, hosts, now)).To(BeFalse())
+
+	// test IP hosts are set as IP subject alternative names
+	hosts = webhookCertHosts(namespace, "192.0.2.10")
+	data, err = generateWebhookCertificates(hosts, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(webhookCertificatesValid(data, hosts, now)).To(BeTrue())
+	block, _ := pem.Decode(data[CertName])
+	g.Expect(block).ToNot(BeNil())
+	cert, err := x509.ParseCertificate(block.Bytes)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cert.DNSNames).ToNot(ContainElement("192.0.2.10"))
+	g.Expect(cert.IPAddresses).To(HaveLen(1))
+	g.Expect(cert.IPAddresses[0].Equal(net.ParseIP("192.0.2.10"))).To(BeTrue())
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	defaultCircuitBreakerReadyPercentThreshold = 50
+	defaultCircuitBreakerSoakPeriod            = 5 * time.Minute
+	// circuitBreakerCheckInterval is the interval at which the readiness of
+	// the webhook deployment is checked while the circuit breaker is
+	// configured, since the deployment is not watched.
+	circuitBreakerCheckInterval = 10 * time.Second
+)
+
+func webhookCircuitBreaker(gatekeeper *operatorv1alpha1.Gatekeeper) *operatorv1alpha1.WebhookCircuitBreakerConfig {
+	if gatekeeper.Spec.Webhook == nil {
+		return nil
+	}
+	return gatekeeper.Spec.Webhook.CircuitBreaker
+}
+
+func circuitBreakerThreshold(circuitBreaker *operatorv1alpha1.WebhookCircuitBreakerConfig) int32 {
+	if circuitBreaker.ReadyPercentThreshold != nil {
+		return *circuitBreaker.ReadyPercentThreshold
+	}
+	return defaultCircuitBreakerReadyPercentThreshold
+}
+
+func circuitBreakerSoakPeriod(circuitBreaker *operatorv1alpha1.WebhookCircuitBreakerConfig) time.Duration {
+	if circuitBreaker.SoakPeriod != nil {
+		return circuitBreaker.SoakPeriod.Duration
+	}
+	return defaultCircuitBreakerSoakPeriod
+}
+
+// updateCircuitBreaker returns whether the webhook failure policy must be set
+// to Ignore given whether the webhook deployment is pending and the
+// percentage of its ready replicas.
+//
+// Without a circuit breaker, the failure policy is set to Ignore while the
+// deployment is pending. Otherwise, this only applies until all the replicas
+// have been ready once. Afterwards, the circuit breaker opens when the
+// readiness drops below the threshold and closes once the readiness has stayed
+// at or above the threshold for the soak period.
+func (r *GatekeeperReconciler) updateCircuitBreaker(gatekeeper *operatorv1alpha1.Gatekeeper, pending bool, readyPercent int32, now time.Time) bool {
+	if !pending {
+		r.webhookHealthy = true
+	}
+	circuitBreaker := webhookCircuitBreaker(gatekeeper)
+	if circuitBreaker == nil {
+		r.closeCircuitBreaker()
+		return pending
+	}
+
+	threshold := circuitBreakerThreshold(circuitBreaker)
+	healthy := readyPercent >= threshold
+	if r.circuitBreakerOpenSince.IsZero() {
+		if !r.webhookHealthy {
+			return pending
+		}
+		if healthy {
+			return false
+		}
+		r.circuitBreakerOpenSince = now
+		r.circuitBreakerHealthySince = time.Time{}
+		webhookCircuitBreakerOpen.Set(1)
+		r.Log.Info("Webhook readiness below threshold, opening circuit breaker", "readyPercent", readyPercent, "threshold", threshold)
+		r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonCircuitBreakerOpen,
+			"%d%% of the replicas of deployment %s are ready, below the %d%% threshold, setting the webhook failure policy to Ignore",
+			readyPercent, WebhookDeploymentName, threshold)
+		return true
+	}
+
+	if !healthy {
+		r.circuitBreakerHealthySince = time.Time{}
+		return true
+	}
+	if r.circuitBreakerHealthySince.IsZero() {
+		r.circuitBreakerHealthySince = now
+	}
+	soakPeriod := circuitBreakerSoakPeriod(circuitBreaker)
+	if now.Sub(r.circuitBreakerHealthySince) < soakPeriod {
+		return true
+	}
+	r.closeCircuitBreaker()
+	r.Log.Info("Webhook readiness recovered, closing circuit breaker", "readyPercent", readyPercent, "soakPeriod", soakPeriod)
+	r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonCircuitBreakerClosed,
+		"%d%% of the replicas of deployment %s have been ready for %s, restoring the configured webhook failure policy",
+		readyPercent, WebhookDeploymentName, soakPeriod)
+	return false
+}
+
+func (r *GatekeeperReconciler) closeCircuitBreaker() {
+	r.circuitBreakerOpenSince = time.Time{}
+	r.circuitBreakerHealthySince = time.Time{}
+	webhookCircuitBreakerOpen.Set(0)
+}
@@ -0,0 +1,148 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+func TestUpdateCircuitBreaker(t *testing.T) {
+	g := NewWithT(t)
+	recorder := record.NewFakeRecorder(10)
+	r := &GatekeeperReconciler{Log: ctrl.Log, Recorder: recorder}
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+		},
+	}
+	now := time.Now()
+
+	// test without circuit breaker
+	g.Expect(r.updateCircuitBreaker(gatekeeper, true, 0, now)).To(BeTrue())
+	g.Expect(r.updateCircuitBreaker(gatekeeper, true, 66, now)).To(BeTrue())
+	g.Expect(r.updateCircuitBreaker(gatekeeper, false, 100, now)).To(BeFalse())
+
+	// test bootstrap
+	threshold := int32(50)
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		CircuitBreaker: &operatorv1alpha1.WebhookCircuitBreakerConfig{
+			ReadyPercentThreshold: &threshold,
+			SoakPeriod:            &metav1.Duration{Duration: time.Minute},
+		},
+	}
+	r.webhookHealthy = false
+	g.Expect(r.updateCircuitBreaker(gatekeeper, true, 66, now)).To(BeTrue())
+	g.Expect(r.updateCircuitBreaker(gatekeeper, false, 100, now)).To(BeFalse())
+
+	// test readiness above threshold after healthy
+	g.Expect(r.updateCircuitBreaker(gatekeeper, true, 66, now)).To(BeFalse())
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// test readiness below threshold opens
+	g.Expect(r.updateCircuitBreaker(gatekeeper, true, 33, now)).To(BeTrue())
+	g.Expect(r.circuitBreakerOpenSince).To(Equal(now))
+	g.Expect(testutil.ToFloat64(webhookCircuitBreakerOpen)).To(Equal(1.0))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning CircuitBreakerOpen 33% of the replicas")))
+
+	// test soak period restarts if readiness drops again
+	g.Expect(r.updateCircuitBreaker(gatekeeper, true, 66, now.Add(time.Minute))).To(BeTrue())
+	g.Expect(r.updateCircuitBreaker(gatekeeper, true, 33, now.Add(90*time.Second))).To(BeTrue())
+	g.Expect(r.updateCircuitBreaker(gatekeeper, false, 100, now.Add(2*time.Minute))).To(BeTrue())
+	g.Expect(r.updateCircuitBreaker(gatekeeper, false, 100, now.Add(150*time.Second))).To(BeTrue())
+
+	// test closes after soak period
+	g.Expect(r.updateCircuitBreaker(gatekeeper, false, 100, now.Add(3*time.Minute))).To(BeFalse())
+	g.Expect(r.circuitBreakerOpenSince.IsZero()).To(BeTrue())
+	g.Expect(testutil.ToFloat64(webhookCircuitBreakerOpen)).To(Equal(0.0))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal CircuitBreakerClosed")))
+}
+
+func TestReconcileCircuitBreaker(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(apiextensionsv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+		},
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Webhook: &operatorv1alpha1.WebhookConfig{
+				CircuitBreaker: &operatorv1alpha1.WebhookCircuitBreakerConfig{},
+			},
+		},
+	}
+	deployment, err := util.GetManifestObject(WebhookFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	deployment.SetNamespace(namespace)
+	g.Expect(unstructured.SetNestedField(deployment.Object, int64(2), "status", "replicas")).To(Succeed())
+	g.Expect(unstructured.SetNestedField(deployment.Object, int64(2), "status", "readyReplicas")).To(Succeed())
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gatekeeper, deployment).WithStatusSubresource(gatekeeper).Build()
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: defaultGatekeeperCrName}}
+
+	// test the readiness is checked periodically while healthy
+	result, err := r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(circuitBreakerCheckInterval))
+	g.Expect(r.circuitBreakerOpenSince.IsZero()).To(BeTrue())
+	g.Expect(validatingWebhookFailurePolicy(g, c)).To(Equal(admregv1.Fail))
+
+	// test a readiness drop opens the circuit breaker
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+	g.Expect(unstructured.SetNestedField(deployment.Object, int64(0), "status", "readyReplicas")).To(Succeed())
+	g.Expect(c.Status().Update(ctx, deployment)).To(Succeed())
+	result, err = r.Reconcile(ctx, req)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+	g.Expect(r.circuitBreakerOpenSince.IsZero()).To(BeFalse())
+	g.Expect(validatingWebhookFailurePolicy(g, c)).To(Equal(admregv1.Ignore))
+}
+
+// validatingWebhookFailurePolicy returns the failure policy of the
+// check-ignore-label webhook, which is Fail unless the webhook fails open.
+func validatingWebhookFailurePolicy(g *WithT, c client.Client) admregv1.FailurePolicyType {
+	webhookConfig := &admregv1.ValidatingWebhookConfiguration{}
+	g.Expect(c.Get(context.Background(), client.ObjectKey{Name: "gatekeeper-validating-webhook-configuration"}, webhookConfig)).To(Succeed())
+	for _, webhook := range webhookConfig.Webhooks {
+		if webhook.Name == CheckIgnoreLabelGatekeeperWebhook {
+			return *webhook.FailurePolicy
+		}
+	}
+	g.Expect(webhookConfig.Webhooks).To(ContainElement(HaveField("Name", CheckIgnoreLabelGatekeeperWebhook)))
+	return ""
+}
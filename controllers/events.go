@@ -0,0 +1,53 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+// Reasons of the events recorded on the Gatekeeper resource.
+const (
+	EventReasonBackedUp             = "BackedUp"
+	EventReasonBackupFailed         = "BackupFailed"
+	EventReasonCircuitBreakerClosed = "CircuitBreakerClosed"
+	EventReasonCircuitBreakerOpen   = "CircuitBreakerOpen"
+	EventReasonCreated              = "Created"
+	EventReasonCRDDeletionBlocked   = "CRDDeletionBlocked"
+	EventReasonDeleted              = "Deleted"
+	EventReasonDeployFailed         = "DeployFailed"
+	EventReasonNamespaceMigrated    = "NamespaceMigrated"
+	EventReasonPolicyNotReady       = "PolicyNotReady"
+	EventReasonProfileCaptured      = "ProfileCaptured"
+	EventReasonProfileCaptureFailed = "ProfileCaptureFailed"
+	EventReasonPruneCandidates      = "PruneCandidates"
+	EventReasonRestored             = "Restored"
+	EventReasonRestoreFailed        = "RestoreFailed"
+	EventReasonUpdated              = "Updated"
+	EventReasonWebhookHostMismatch  = "WebhookHostMismatch"
+	EventReasonWebhookPending       = "WebhookPending"
+	EventReasonWebhookReady         = "WebhookReady"
+)
+
+// recordEvent records an event on the Gatekeeper resource. Events are not
+// recorded if no recorder is configured e.g. in unit tests.
+func (r *GatekeeperReconciler) recordEvent(gatekeeper *operatorv1alpha1.Gatekeeper, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(gatekeeper, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+func TestRecordEvent(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "gatekeeper",
+		},
+	}
+
+	// test without recorder
+	r := &GatekeeperReconciler{}
+	r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonCreated, "Created %s", "test")
+
+	// test with recorder
+	recorder := record.NewFakeRecorder(1)
+	r.Recorder = recorder
+	r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonWebhookPending, "Deployment %s is not ready", WebhookDeploymentName)
+	g.Expect(recorder.Events).To(Receive(Equal("Warning WebhookPending Deployment gatekeeper-controller-manager is not ready")))
+}
+
+func TestCrudObjectEvents(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Recorder: recorder}
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+		},
+	}
+	configMap := func(value string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetNamespace(namespace)
+		obj.SetName("test")
+		g.Expect(unstructured.SetNestedField(obj.Object, value, "data", "key")).To(Succeed())
+		return obj
+	}
+
+	operation, err := r.crudObject(ctx, configMap("a"), gatekeeper, apply)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(operation).To(Equal(assetOperationCreate))
+	g.Expect(recorder.Events).To(Receive(Equal("Normal Created Created ConfigMap mygatekeeper/test")))
+
+	// test no event is recorded if the object is unchanged
+	operation, err = r.crudObject(ctx, configMap("a"), gatekeeper, apply)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(operation).To(Equal(assetOperationUpdate))
+	g.Expect(recorder.Events).ToNot(Receive())
+
+	// test an event is recorded if the object changed
+	operation, err = r.crudObject(ctx, configMap("b"), gatekeeper, apply)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(operation).To(Equal(assetOperationUpdate))
+	g.Expect(recorder.Events).To(Receive(Equal("Normal Updated Updated ConfigMap mygatekeeper/test")))
+
+	operation, err = r.crudObject(ctx, configMap("b"), gatekeeper, delete)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(operation).To(Equal(assetOperationDelete))
+	g.Expect(recorder.Events).To(Receive(Equal("Normal Deleted Deleted ConfigMap mygatekeeper/test")))
+}
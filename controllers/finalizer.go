@@ -0,0 +1,243 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+// GatekeeperFinalizer is set on the Gatekeeper resource so that the operator
+// tears down the Gatekeeper resources according to the deletion policy
+// before it is removed.
+const GatekeeperFinalizer = "operator.gatekeeper.sh/cleanup"
+
+func deletionPolicy(gatekeeper *operatorv1alpha1.Gatekeeper) operatorv1alpha1.DeletionPolicy {
+	if gatekeeper.Spec.DeletionPolicy != nil {
+		return *gatekeeper.Spec.DeletionPolicy
+	}
+	return operatorv1alpha1.DeletionPolicyRemoveWorkloadsOnly
+}
+
+func isCRDAsset(asset string) bool {
+	obj, err := util.GetManifestObject(asset)
+	return err == nil && obj.GetKind() == "CustomResourceDefinition"
+}
+
+// ensureFinalizer adds the finalizer to the Gatekeeper resource if missing.
+func (r *GatekeeperReconciler) ensureFinalizer(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	if controllerutil.ContainsFinalizer(gatekeeper, GatekeeperFinalizer) {
+		return nil
+	}
+	patch := client.MergeFrom(gatekeeper.DeepCopy())
+	controllerutil.AddFinalizer(gatekeeper, GatekeeperFinalizer)
+	if err := r.Patch(ctx, gatekeeper, patch); err != nil {
+		return errors.Wrapf(err, "Unable to add finalizer %s", GatekeeperFinalizer)
+	}
+	return nil
+}
+
+// finalizeGatekeeper tears down the Gatekeeper resources according to the
+// deletion policy of the deleted Gatekeeper resource and removes its
+// finalizer.
+//
+// The webhook configurations are always deleted first so that admissions are
+// never blocked on a webhook whose pods are already gone. The resources that
+// are kept have their owner reference to the Gatekeeper resource removed so
+// that the garbage collector does not cascade the deletion, in particular to
+// the CRDs and thus to every constraint template, constraint and mutator. The
+// policy archives are only deleted by the Purge policy. The Gatekeeper
+// namespace is never deleted.
+func (r *GatekeeperReconciler) finalizeGatekeeper(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	if !controllerutil.ContainsFinalizer(gatekeeper, GatekeeperFinalizer) {
+		return nil
+	}
+
+	policy := deletionPolicy(gatekeeper)
+	r.Log.Info("Finalizing Gatekeeper", "deletionPolicy", policy)
+
+	applyMonitoringAssets, deleteMonitoringAssets := getMonitoringAssets(gatekeeper, r.isOpenShift(), r.PlatformInfo.HasPrometheusOperator())
+	workloadAssets := append(applyMonitoringAssets, deleteMonitoringAssets...)
+	var crdAssets []string
+	for i := len(orderedStaticAssets) - 1; i >= 0; i-- {
+		switch asset := orderedStaticAssets[i]; {
+		case asset == NamespaceFile:
+		case policy != operatorv1alpha1.DeletionPolicyPurge && isCRDAsset(asset):
+			crdAssets = append(crdAssets, asset)
+		default:
+			workloadAssets = append(workloadAssets, asset)
+		}
+	}
+
+	// Gatekeeper is torn down from both namespaces if it is deleted while
+	// being migrated.
+	namespaces := []string{r.gatekeeperNamespace()}
+	if source := r.migrationSource(gatekeeper); source != "" {
+		namespaces = append(namespaces, source)
+	}
+
+	var err error
+	if policy == operatorv1alpha1.DeletionPolicyRetain {
+		err = r.releaseAssets(ctx, webhookStaticAssets, r.gatekeeperNamespace(), gatekeeper)
+		for _, namespace := range namespaces {
+			for _, obj := range r.webhookExposureObjects(namespace) {
+				if err == nil {
+					err = r.releaseObject(ctx, obj, gatekeeper)
+				}
+			}
+			if err == nil {
+				err = r.releaseAssets(ctx, workloadAssets, namespace, gatekeeper)
+			}
+		}
+	} else {
+		err = r.teardownAssets(ctx, webhookStaticAssets, r.gatekeeperNamespace(), gatekeeper)
+		for _, namespace := range namespaces {
+			for _, obj := range r.webhookExposureObjects(namespace) {
+				if err == nil {
+					err = r.crudResource(ctx, webhookExposureAsset(obj), obj, gatekeeper, delete)
+				}
+			}
+			if err == nil {
+				err = r.teardownAssets(ctx, workloadAssets, namespace, gatekeeper)
+			}
+		}
+	}
+	if err == nil {
+		err = r.releaseAssets(ctx, crdAssets, r.gatekeeperNamespace(), gatekeeper)
+	}
+	// The policy archives are only deleted along with the CRDs of the
+	// policies they hold. Otherwise, the archives of the namespace Gatekeeper
+	// was being migrated from are moved so that they are all kept together.
+	for _, namespace := range namespaces {
+		switch {
+		case err != nil:
+		case policy == operatorv1alpha1.DeletionPolicyPurge:
+			err = r.deleteArchives(ctx, namespace)
+		case namespace != r.gatekeeperNamespace():
+			err = r.moveArchives(ctx, namespace)
+		}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Unable to finalize Gatekeeper with deletion policy %s", policy)
+	}
+
+	patch := client.MergeFrom(gatekeeper.DeepCopy())
+	controllerutil.RemoveFinalizer(gatekeeper, GatekeeperFinalizer)
+	if err := r.Patch(ctx, gatekeeper, patch); err != nil {
+		return errors.Wrapf(err, "Unable to remove finalizer %s", GatekeeperFinalizer)
+	}
+
+	// Start afresh if the Gatekeeper resource is created again.
+	r.closeCircuitBreaker()
+	r.resetPolicyReadyGate(false)
+	r.webhookHealthy = false
+	r.webhookPendingSince = time.Time{}
+	r.lastWebhookProbe = time.Time{}
+	r.webhookSLOSamples = nil
+	r.pruneScan = ""
+	return nil
+}
+
+// teardownAssets deletes the given assets from the given Gatekeeper namespace
+// in order.
+func (r *GatekeeperReconciler) teardownAssets(ctx context.Context, assets []string, namespace string, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	for _, asset := range assets {
+		obj, err := util.GetManifestObject(asset)
+		if err != nil {
+			return err
+		}
+		if err := setNamespace(obj, asset, namespace); err != nil {
+			return err
+		}
+		if err := r.crudResource(ctx, asset, obj, gatekeeper, delete); err != nil {
+			return err
+		}
+		switch asset {
+		case ValidatingWebhookConfiguration, MutatingWebhookConfiguration:
+			recordWebhookFailurePolicies(obj, true)
+		}
+	}
+	return nil
+}
+
+// webhookExposureObjects returns the resources in the given Gatekeeper
+// namespace that may expose the webhook at the configured URL.
+func (r *GatekeeperReconciler) webhookExposureObjects(namespace string) []*unstructured.Unstructured {
+	objs := []*unstructured.Unstructured{webhookLoadBalancerService(namespace, defaultWebhookURLPort, "")}
+	// The Route API is only available on OpenShift.
+	if r.isOpenShift() {
+		objs = append(objs, webhookRoute(namespace, ""))
+	}
+	return objs
+}
+
+// releaseAssets removes the owner reference to the Gatekeeper resource from
+// the given assets in the given Gatekeeper namespace so that they outlive it.
+func (r *GatekeeperReconciler) releaseAssets(ctx context.Context, assets []string, namespace string, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	for _, asset := range assets {
+		obj, err := util.GetManifestObject(asset)
+		if err != nil {
+			return err
+		}
+		if err := setNamespace(obj, asset, namespace); err != nil {
+			return err
+		}
+		if err := r.releaseObject(ctx, obj, gatekeeper); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GatekeeperReconciler) releaseObject(ctx context.Context, obj *unstructured.Unstructured, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	namespacedName := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	clusterObj := &unstructured.Unstructured{}
+	clusterObj.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := r.Get(ctx, namespacedName, clusterObj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "Error attempting to get resource %s", namespacedName)
+	}
+
+	var ownerReferences []metav1.OwnerReference
+	for _, ref := range clusterObj.GetOwnerReferences() {
+		if ref.UID != gatekeeper.GetUID() {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	if len(ownerReferences) == len(clusterObj.GetOwnerReferences()) {
+		return nil
+	}
+	clusterObj.SetOwnerReferences(ownerReferences)
+	if err := r.Update(ctx, clusterObj); err != nil {
+		return errors.Wrapf(err, "Error attempting to release resource %s", namespacedName)
+	}
+	r.Log.Info("Released Gatekeeper resource", "Gatekeeper resource", namespacedName)
+	return nil
+}
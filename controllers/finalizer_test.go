@@ -0,0 +1,126 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+func TestFinalizeGatekeeper(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(apiextensionsv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	assets := []string{ValidatingWebhookConfiguration, WebhookFile, ConstraintTemplateCRDFile}
+	tests := []struct {
+		policy   *operatorv1alpha1.DeletionPolicy
+		retained []string
+	}{
+		{nil, []string{ConstraintTemplateCRDFile}},
+		{deletionPolicyPtr(operatorv1alpha1.DeletionPolicyRetain), assets},
+		{deletionPolicyPtr(operatorv1alpha1.DeletionPolicyRemoveWorkloadsOnly), []string{ConstraintTemplateCRDFile}},
+		{deletionPolicyPtr(operatorv1alpha1.DeletionPolicyPurge), nil},
+	}
+	for _, test := range tests {
+		now := metav1.Now()
+		gatekeeper := &operatorv1alpha1.Gatekeeper{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              defaultGatekeeperCrName,
+				UID:               types.UID("gatekeeper-uid"),
+				Finalizers:        []string{GatekeeperFinalizer},
+				DeletionTimestamp: &now,
+			},
+			Spec: operatorv1alpha1.GatekeeperSpec{
+				DeletionPolicy: test.policy,
+			},
+		}
+		archive := newArchiveChunk(operatorv1alpha1.BackupStorageSecret, namespace, archiveChunkName("nightly", 0), "nightly")
+		objs := []client.Object{gatekeeper, archive}
+		for _, asset := range assets {
+			obj, err := util.GetManifestObject(asset)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(setNamespace(obj, asset, namespace)).To(Succeed())
+			g.Expect(ctrl.SetControllerReference(gatekeeper, obj, scheme)).To(Succeed())
+			objs = append(objs, obj)
+		}
+
+		var deleted []string
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+			Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+				deleted = append(deleted, obj.GetObjectKind().GroupVersionKind().Kind)
+				return c.Delete(ctx, obj, opts...)
+			},
+		}).Build()
+		r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace}
+
+		g.Expect(r.finalizeGatekeeper(ctx, gatekeeper)).To(Succeed())
+		if len(deleted) > 0 {
+			g.Expect(deleted[0]).To(Equal("ValidatingWebhookConfiguration"))
+		}
+		err := c.Get(ctx, types.NamespacedName{Name: defaultGatekeeperCrName}, &operatorv1alpha1.Gatekeeper{})
+		g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		for _, asset := range assets {
+			manifest, err := util.GetManifestObject(asset)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(setNamespace(manifest, asset, namespace)).To(Succeed())
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(manifest.GroupVersionKind())
+			err = c.Get(ctx, client.ObjectKeyFromObject(manifest), obj)
+			retained := false
+			for _, a := range test.retained {
+				retained = retained || a == asset
+			}
+			if retained {
+				g.Expect(err).ToNot(HaveOccurred(), asset)
+				g.Expect(obj.GetOwnerReferences()).To(BeEmpty(), asset)
+			} else {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), asset)
+			}
+		}
+
+		err = c.Get(ctx, client.ObjectKeyFromObject(archive), &corev1.Secret{})
+		if test.policy != nil && *test.policy == operatorv1alpha1.DeletionPolicyPurge {
+			g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		} else {
+			g.Expect(err).ToNot(HaveOccurred())
+		}
+	}
+}
+
+func deletionPolicyPtr(policy operatorv1alpha1.DeletionPolicy) *operatorv1alpha1.DeletionPolicy {
+	return &policy
+}
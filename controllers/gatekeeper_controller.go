@@ -22,17 +22,22 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	admregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -41,6 +46,7 @@ import (
 	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
 	"github.com/gatekeeper/gatekeeper-operator/controllers/merge"
 	"github.com/gatekeeper/gatekeeper-operator/pkg/platform"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/tracing"
 	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
 )
 
@@ -48,6 +54,9 @@ const (
 	defaultGatekeeperCrName           = "gatekeeper"
 	GatekeeperImageEnvVar             = "RELATED_IMAGE_GATEKEEPER"
 	NamespaceFile                     = "v1_namespace_gatekeeper-system.yaml"
+	ConfigCRDFile                     = "apiextensions.k8s.io_v1_customresourcedefinition_configs.config.gatekeeper.sh.yaml"
+	ConstraintTemplateCRDFile         = "apiextensions.k8s.io_v1_customresourcedefinition_constrainttemplates.templates.gatekeeper.sh.yaml"
+	ExpansionTemplateCRDFile          = "apiextensions.k8s.io_v1_customresourcedefinition_expansiontemplate.expansion.gatekeeper.sh.yaml"
 	AssignCRDFile                     = "apiextensions.k8s.io_v1_customresourcedefinition_assign.mutations.gatekeeper.sh.yaml"
 	AssignMetadataCRDFile             = "apiextensions.k8s.io_v1_customresourcedefinition_assignmetadata.mutations.gatekeeper.sh.yaml"
 	MutatorPodStatusCRDFile           = "apiextensions.k8s.io_v1_customresourcedefinition_mutatorpodstatuses.status.gatekeeper.sh.yaml"
@@ -60,6 +69,8 @@ const (
 	RoleFile                          = "rbac.authorization.k8s.io_v1_role_gatekeeper-manager-role.yaml"
 	RoleBindingFile                   = "rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-manager-rolebinding.yaml"
 	ServerCertFile                    = "v1_secret_gatekeeper-webhook-server-cert.yaml"
+	WebhookServiceFile                = "v1_service_gatekeeper-webhook-service.yaml"
+	MetricsServiceFile                = "v1_service_gatekeeper-metrics-service.yaml"
 	ValidatingWebhookConfiguration    = "admissionregistration.k8s.io_v1_validatingwebhookconfiguration_gatekeeper-validating-webhook-configuration.yaml"
 	MutatingWebhookConfiguration      = "admissionregistration.k8s.io_v1_mutatingwebhookconfiguration_gatekeeper-mutating-webhook-configuration.yaml"
 	ValidationGatekeeperWebhook       = "validation.gatekeeper.sh"
@@ -81,17 +92,37 @@ const (
 	OperationMutationStatus           = "mutation-status"
 	OperationMutationWebhook          = "mutation-webhook"
 	DisabledBuiltinArg                = "--disable-opa-builtin"
+	DisableCertRotationArg            = "--disable-cert-rotation"
+	PortArg                           = "--port"
+	HealthAddrArg                     = "--health-addr"
+	PrometheusPortArg                 = "--prometheus-port"
+	MetricsBackendArg                 = "--metrics-backend"
+	OTLPEndpointArg                   = "--otlp-endpoint"
+	OTLPInsecureEnvVar                = "OTEL_EXPORTER_OTLP_INSECURE"
+	webhookServerPortName             = "webhook-server"
+	healthzPortName                   = "healthz"
+	metricsPortName                   = "metrics"
+	WebhookServiceName                = "gatekeeper-webhook-service"
+	WebhookServicePortName            = "https-webhook-server"
+	defaultWebhookURLPort             = 443
+	SystemLabel                       = "gatekeeper.sh/system"
+	PodSecurityLabelPrefix            = "pod-security.kubernetes.io/"
+	ManagedByLabel                    = "app.kubernetes.io/managed-by"
+	PartOfLabel                       = "app.kubernetes.io/part-of"
+	VersionLabel                      = "app.kubernetes.io/version"
+	managedByOperator                 = "gatekeeper-operator"
+	partOfGatekeeper                  = "gatekeeper"
 )
 
 var (
 	orderedStaticAssets = []string{
 		NamespaceFile,
 		"v1_resourcequota_gatekeeper-critical-pods.yaml",
-		"apiextensions.k8s.io_v1_customresourcedefinition_configs.config.gatekeeper.sh.yaml",
-		"apiextensions.k8s.io_v1_customresourcedefinition_constrainttemplates.templates.gatekeeper.sh.yaml",
+		ConfigCRDFile,
+		ConstraintTemplateCRDFile,
 		"apiextensions.k8s.io_v1_customresourcedefinition_constrainttemplatepodstatuses.status.gatekeeper.sh.yaml",
 		"apiextensions.k8s.io_v1_customresourcedefinition_constraintpodstatuses.status.gatekeeper.sh.yaml",
-		"apiextensions.k8s.io_v1_customresourcedefinition_expansiontemplate.expansion.gatekeeper.sh.yaml",
+		ExpansionTemplateCRDFile,
 		ModifySetCRDFile,
 		ProviderCRDFile,
 		AssignCRDFile,
@@ -106,13 +137,22 @@ var (
 		RoleBindingFile,
 		AuditFile,
 		WebhookFile,
-		"v1_service_gatekeeper-webhook-service.yaml",
+		WebhookServiceFile,
+		MetricsServiceFile,
 	}
 	webhookStaticAssets = []string{
 		ValidatingWebhookConfiguration,
 		MutatingWebhookConfiguration,
 	}
 
+	// requestAnnotations are the annotations of the Gatekeeper resource
+	// requesting an operation from the operator.
+	requestAnnotations = []string{
+		CaptureProfileAnnotation,
+		BackupAnnotation,
+		RestoreAnnotation,
+	}
+
 	MutatingCRDs = []string{
 		AssignCRDFile,
 		AssignMetadataCRDFile,
@@ -123,10 +163,73 @@ var (
 // GatekeeperReconciler reconciles a Gatekeeper object
 type GatekeeperReconciler struct {
 	client.Client
-	Log          logr.Logger
-	Scheme       *runtime.Scheme
-	Namespace    string
-	PlatformInfo platform.PlatformInfo
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Namespace is the namespace Gatekeeper is deployed to unless the
+	// Gatekeeper resource sets spec.namespace.
+	Namespace string
+	// OperatorNamespace is the namespace the operator runs in. The operator
+	// metrics are not monitored if empty.
+	OperatorNamespace string
+	PlatformInfo      platform.PlatformInfo
+	Recorder          record.EventRecorder
+	// ProfileFetcher fetches the profiles requested with the
+	// CaptureProfileAnnotation. Profiles cannot be captured if nil.
+	ProfileFetcher ProfileFetcher
+
+	// webhookPendingSince is when the webhook deployment was first seen not
+	// ready, or zero if it is ready.
+	webhookPendingSince time.Time
+	// webhookHealthy is whether all the replicas of the webhook deployment
+	// have been ready since the operator started.
+	webhookHealthy bool
+	// circuitBreakerOpenSince is when the webhook circuit breaker opened, or
+	// zero if it is closed.
+	circuitBreakerOpenSince time.Time
+	// circuitBreakerHealthySince is when the readiness of the webhook
+	// deployment recovered while the circuit breaker is open.
+	circuitBreakerHealthySince time.Time
+	// policyGateSince is when the policy ready gate started waiting for the
+	// policies to be enforced on all webhook pods, or zero if it is not
+	// waiting.
+	policyGateSince time.Time
+	// policyGateTimedOut is whether the policy ready gate timed out while
+	// waiting.
+	policyGateTimedOut bool
+	// policyReady is whether the policies have been enforced on all webhook
+	// pods since the webhook deployment was last ready.
+	policyReady bool
+	// lastWebhookProbe is when the webhooks were last probed.
+	lastWebhookProbe time.Time
+	// probeMutatorDeleted is whether the probe mutator was deleted since the
+	// prober or the mutating webhook was disabled.
+	probeMutatorDeleted bool
+	// probeConstraintDeleted is whether the probe constraint template was
+	// deleted since the prober or the validating webhook was disabled.
+	probeConstraintDeleted bool
+	// webhookSLOSamples are the webhook metrics of the previous evaluation of
+	// the webhook SLO by pod name.
+	webhookSLOSamples map[string]webhookMetricsSample
+	// lastWebhookSLOEvaluation is when the webhook SLO was last evaluated.
+	lastWebhookSLOEvaluation time.Time
+	// webhookSLOBreach is the result of the last evaluation of the webhook
+	// SLO.
+	webhookSLOBreach string
+	// restoreSince is when the pending restore of a policy archive started,
+	// or zero if no restore is pending.
+	restoreSince time.Time
+	// pruneScan identifies the Gatekeeper resource, manifest version,
+	// namespace and pruning mode of the last successful scan for obsolete
+	// resources, which is only repeated once one of them changes.
+	pruneScan string
+	// namespace is the namespace Gatekeeper is deployed to by the current
+	// reconciliation.
+	namespace string
+	// profileCaptureMu guards profileCaptureDone.
+	profileCaptureMu sync.Mutex
+	// profileCaptureDone is closed once the last profile capture started in
+	// the background completes, or nil if none was started.
+	profileCaptureDone chan struct{}
 }
 
 type crudOperation uint32
@@ -148,6 +251,7 @@ const (
 // Cluster Scoped
 // +kubebuilder:rbac:groups=*,resources=*,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=config.gatekeeper.sh,resources=configs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=config.gatekeeper.sh,resources=configs/status,verbs=get;update;patch
@@ -161,13 +265,17 @@ const (
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;clusterrolebindings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups=expansion.gatekeeper.sh,resources=expansiontemplate,verbs=create;delete;get;list;patch;update;watch
 // +kubebuilder:rbac:groups=externaldata.gatekeeper.sh,resources=providers,verbs=create;delete;get;list;patch;update;watch
 
 // Namespace Scoped
-// +kubebuilder:rbac:groups=core,namespace="system",resources=secrets;serviceaccounts;services;resourcequotas,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,namespace="system",resources=pods/portforward,verbs=create
+// +kubebuilder:rbac:groups=core,namespace="system",resources=configmaps;secrets;serviceaccounts;services;resourcequotas,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,namespace="system",resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,namespace="system",resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=policy,namespace="system",resources=poddisruptionbudgets,verbs=create;delete;update;use
+// +kubebuilder:rbac:groups=route.openshift.io,namespace="system",resources=routes;routes/custom-host,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,namespace="system",resources=prometheusrules;servicemonitors,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -182,6 +290,16 @@ func (r *GatekeeperReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	logger := r.Log.WithValues("gatekeeper", req.NamespacedName)
 	logger.Info("Reconciling Gatekeeper")
 
+	ctx, span := tracing.StartSpan(ctx, "Reconcile", attribute.String("gatekeeper", req.Name))
+	start := time.Now()
+	result := reconcileResultSuccess
+	var err error
+	defer func() {
+		reconcileDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+		span.SetAttributes(attribute.String("result", result))
+		tracing.EndSpan(span, err)
+	}()
+
 	if req.Name != defaultGatekeeperCrName {
 		err := fmt.Errorf("Gatekeeper resource name must be '%s'", defaultGatekeeperCrName)
 		logger.Error(err, "Invalid Gatekeeper resource name")
@@ -190,11 +308,27 @@ func (r *GatekeeperReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	gatekeeper := &operatorv1alpha1.Gatekeeper{}
-	err := r.Get(ctx, req.NamespacedName, gatekeeper)
+	err = r.Get(ctx, req.NamespacedName, gatekeeper)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
+			err = nil
 			return ctrl.Result{}, nil
 		}
+		result = reconcileResultError
+		return ctrl.Result{}, err
+	}
+	r.namespace = r.desiredNamespace(gatekeeper)
+
+	if gatekeeper.DeletionTimestamp != nil {
+		if err = r.finalizeGatekeeper(ctx, gatekeeper); err != nil {
+			result = reconcileResultError
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if err = r.ensureFinalizer(ctx, gatekeeper); err != nil {
+		result = reconcileResultError
 		return ctrl.Result{}, err
 	}
 
@@ -203,26 +337,94 @@ func (r *GatekeeperReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			"spec.image.image", gatekeeper.Spec.Image.Image)
 	}
 
-	err, requeue := r.deployGatekeeperResources(gatekeeper)
+	err, state := r.deployGatekeeperResources(ctx, gatekeeper)
 	if err != nil {
+		result = reconcileResultError
+		r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonDeployFailed, "Unable to deploy Gatekeeper resources: %v", err)
 		return ctrl.Result{}, errors.Wrap(err, "Unable to deploy Gatekeeper resources")
-	} else if requeue {
+	}
+
+	if !state.failOpen && !state.migrating {
+		if state.sloBreach, err = r.evaluateWebhookSLO(ctx, gatekeeper, time.Now()); err != nil {
+			// The previous result is kept until the SLO is evaluated again
+			// at the next interval.
+			logger.Error(err, "Unable to evaluate the webhook SLO")
+			err = nil
+		}
+	}
+
+	state.webhookProbes = r.probeWebhooks(ctx, gatekeeper, time.Now())
+
+	if state.images, err = r.podImages(ctx); err != nil {
+		// The images are collected again at the next reconciliation.
+		logger.Error(err, "Unable to collect the images of the Gatekeeper pods")
+		state.images, err = gatekeeper.Status.Images, nil
+	}
+
+	if err = r.updateWebhookStatus(ctx, gatekeeper, state); err != nil {
+		result = reconcileResultError
+		return ctrl.Result{}, err
+	}
+
+	if err = r.captureProfile(ctx, gatekeeper); err != nil {
+		result = reconcileResultError
+		return ctrl.Result{}, err
+	}
+
+	restorePending, err := r.handlePolicyArchives(ctx, gatekeeper)
+	if err != nil {
+		result = reconcileResultError
+		return ctrl.Result{}, err
+	}
+
+	if state.failOpen {
+		result = reconcileResultRequeue
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
+	if state.migrating {
+		result = reconcileResultRequeue
+		return ctrl.Result{RequeueAfter: migrationRetryInterval}, nil
+	}
+
+	// The mutators are not watched, check again later whether they still
+	// block the deletion of the mutating CRDs.
+	interval := periodicReconcileInterval(gatekeeper)
+	if state.blockedMutators > 0 && (interval == 0 || blockedMutatorsRequeueInterval < interval) {
+		interval = blockedMutatorsRequeueInterval
+	}
+	if restorePending && (interval == 0 || restoreRetryInterval < interval) {
+		interval = restoreRetryInterval
+	}
+	if interval > 0 {
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GatekeeperReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := registerMetrics(r); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorv1alpha1.Gatekeeper{}).
 		WithEventFilter(predicate.Funcs{
 			UpdateFunc: func(e event.UpdateEvent) bool {
 				oldGeneration := e.ObjectOld.GetGeneration()
 				newGeneration := e.ObjectNew.GetGeneration()
+				for _, annotation := range requestAnnotations {
+					oldRequest := e.ObjectOld.GetAnnotations()[annotation]
+					newRequest := e.ObjectNew.GetAnnotations()[annotation]
+					if newRequest != "" && oldRequest != newRequest {
+						return true
+					}
+				}
 
-				return oldGeneration != newGeneration
+				deleting := e.ObjectNew.GetDeletionTimestamp() != nil
+
+				return oldGeneration != newGeneration || deleting
 			},
 			DeleteFunc: func(e event.DeleteEvent) bool {
 				return false
@@ -231,53 +433,137 @@ func (r *GatekeeperReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
-func (r *GatekeeperReconciler) deployGatekeeperResources(gatekeeper *operatorv1alpha1.Gatekeeper) (error, bool) {
+// periodicReconcileInterval returns the interval at which the Gatekeeper
+// resource must be reconciled to evaluate the webhook SLO, probe the webhooks
+// and check the readiness of the webhook deployment for the circuit breaker,
+// or zero if none of them is configured.
+func periodicReconcileInterval(gatekeeper *operatorv1alpha1.Gatekeeper) time.Duration {
+	interval := webhookSLOInterval(gatekeeper)
+	if probeInterval := webhookProbeInterval(gatekeeper); probeInterval > 0 && (interval == 0 || probeInterval < interval) {
+		interval = probeInterval
+	}
+	if webhookCircuitBreaker(gatekeeper) != nil && (interval == 0 || circuitBreakerCheckInterval < interval) {
+		interval = circuitBreakerCheckInterval
+	}
+	return interval
+}
+
+func (r *GatekeeperReconciler) deployGatekeeperResources(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper) (error, webhookState) {
+	source := r.migrationSource(gatekeeper)
+	state := webhookState{namespace: r.deployedNamespace(gatekeeper), migrating: source != ""}
 	deleteWebhookAssets, applyOrderedAssets, applyWebhookAssets, deleteCRDAssets := getStaticAssets(gatekeeper)
 
-	if err := r.deleteAssets(deleteWebhookAssets, gatekeeper); err != nil {
-		return err, false
+	if err := r.validateImageDigests(gatekeeper); err != nil {
+		return err, state
+	}
+
+	if err := r.deleteAssets(ctx, deleteWebhookAssets, gatekeeper); err != nil {
+		return err, state
+	}
+
+	// The webhook in the namespace Gatekeeper is migrated from keeps
+	// enforcing policies until the webhook in the new namespace is ready, but
+	// it must first admit the new namespace.
+	if source != "" {
+		pending, err := r.exemptMigrationTarget(ctx, source)
+		if err != nil || pending {
+			return err, state
+		}
 	}
 
 	// Checking for deployment before deploying assets or deleting CRDs to
 	// avoid transient errors e.g. cert rotator errors, removing required CRD
 	// resources, etc.
-	err, requeue := r.validateWebhookDeployment()
+	err, pending, readyPercent := r.validateWebhookDeployment(ctx)
 	if err != nil {
-		return err, false
+		return err, state
+	}
+	now := time.Now()
+	deploymentFailOpen := r.updateCircuitBreaker(gatekeeper, pending, readyPercent, now)
+
+	if err := r.applyAssets(ctx, applyOrderedAssets, gatekeeper, false); err != nil {
+		return err, state
+	}
+
+	if err := r.applyWebhookExposure(ctx, gatekeeper); err != nil {
+		return err, state
+	}
+
+	if err := r.applyMonitoringAssets(ctx, gatekeeper); err != nil {
+		return err, state
+	}
+
+	state.policyNotReady, state.policyTimedOut = r.updatePolicyReadyGate(ctx, gatekeeper, deploymentFailOpen, now)
+	state.failOpen = deploymentFailOpen || state.policyNotReady != ""
+	if source != "" && state.failOpen {
+		r.Log.Info("Waiting for Gatekeeper to be ready before migrating the webhooks", "from", source, "to", r.gatekeeperNamespace())
+		return nil, state
+	}
+	webhookWasPending := !r.webhookPendingSince.IsZero()
+	r.recordWebhookReadiness(state.failOpen, now)
+	switch {
+	case deploymentFailOpen && !webhookWasPending:
+		r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonWebhookPending,
+			"Deployment %s is not ready, setting the webhook failure policy to Ignore", WebhookDeploymentName)
+	case !state.failOpen && webhookWasPending:
+		r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonWebhookReady,
+			"Deployment %s is ready, restoring the configured webhook failure policy", WebhookDeploymentName)
+	}
+
+	if err := r.applyAssets(ctx, applyWebhookAssets, gatekeeper, state.failOpen); err != nil {
+		return err, state
 	}
 
-	if err := r.applyAssets(applyOrderedAssets, gatekeeper, false); err != nil {
-		return err, false
+	if source != "" {
+		if err := r.teardownMigrationSource(ctx, gatekeeper, source); err != nil {
+			return err, state
+		}
+		state.namespace, state.migrating = r.gatekeeperNamespace(), false
+		r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonNamespaceMigrated,
+			"Migrated Gatekeeper from namespace %s to %s", source, r.gatekeeperNamespace())
 	}
 
-	if err := r.applyAssets(applyWebhookAssets, gatekeeper, requeue); err != nil {
-		return err, false
+	if state.blockedMutators, err = r.guardMutatingCRDDeletion(ctx, gatekeeper, deleteCRDAssets); err != nil {
+		return err, state
+	}
+	if state.blockedMutators == 0 {
+		if err := r.deleteAssets(ctx, deleteCRDAssets, gatekeeper); err != nil {
+			return err, state
+		}
 	}
 
-	if err := r.deleteAssets(deleteCRDAssets, gatekeeper); err != nil {
-		return err, false
+	if state.pruneCandidates, err = r.pruneObsoleteResources(ctx, gatekeeper); err != nil {
+		return err, state
 	}
 
-	return nil, requeue
+	return nil, state
 }
 
-func (r *GatekeeperReconciler) deleteAssets(assets []string, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+func (r *GatekeeperReconciler) deleteAssets(ctx context.Context, assets []string, gatekeeper *operatorv1alpha1.Gatekeeper) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "deleteAssets", attribute.StringSlice("assets", assets))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	for _, a := range assets {
 		obj, err := util.GetManifestObject(a)
 		if err != nil {
 			return err
 		}
 
-		if err = r.crudResource(obj, gatekeeper, delete); err != nil {
+		if err = r.crudResource(ctx, a, obj, gatekeeper, delete); err != nil {
 			return err
 		}
+
+		switch a {
+		case ValidatingWebhookConfiguration, MutatingWebhookConfiguration:
+			recordWebhookFailurePolicies(obj, true)
+		}
 	}
 	return nil
 }
 
-func (r *GatekeeperReconciler) applyAssets(assets []string, gatekeeper *operatorv1alpha1.Gatekeeper, controllerDeploymentPending bool) error {
+func (r *GatekeeperReconciler) applyAssets(ctx context.Context, assets []string, gatekeeper *operatorv1alpha1.Gatekeeper, controllerDeploymentPending bool) error {
 	for _, a := range assets {
-		err := r.applyAsset(gatekeeper, a, controllerDeploymentPending)
+		err := r.applyAsset(ctx, gatekeeper, a, controllerDeploymentPending)
 		if err != nil {
 			return err
 		}
@@ -285,35 +571,66 @@ func (r *GatekeeperReconciler) applyAssets(assets []string, gatekeeper *operator
 	return nil
 }
 
-func (r *GatekeeperReconciler) applyAsset(gatekeeper *operatorv1alpha1.Gatekeeper, asset string, controllerDeploymentPending bool) error {
+func (r *GatekeeperReconciler) applyAsset(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, asset string, controllerDeploymentPending bool) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "applyAsset", attribute.String("asset", asset))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	obj, err := util.GetManifestObject(asset)
 	if err != nil {
 		return err
 	}
 
-	if err = crOverrides(gatekeeper, asset, obj, r.Namespace, r.isOpenShift(), controllerDeploymentPending); err != nil {
+	if err = crOverrides(gatekeeper, asset, obj, r.gatekeeperNamespace(), r.isOpenShift(), controllerDeploymentPending); err != nil {
+		return err
+	}
+	if source := r.migrationSource(gatekeeper); source != "" {
+		if err = r.migrationOverrides(ctx, asset, obj, source); err != nil {
+			return err
+		}
+	}
+
+	switch asset {
+	case ServerCertFile:
+		err = r.setWebhookServerCertData(ctx, obj, gatekeeper.Spec.Webhook)
+	case ValidatingWebhookConfiguration, MutatingWebhookConfiguration:
+		err = r.setWebhookCABundle(ctx, obj, gatekeeper.Spec.Webhook)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err = r.crudResource(obj, gatekeeper, apply); err != nil {
+	setInventoryLabels(obj, gatekeeper)
+	if err = r.crudResource(ctx, asset, obj, gatekeeper, apply); err != nil {
 		return err
 	}
+
+	switch asset {
+	case ValidatingWebhookConfiguration, MutatingWebhookConfiguration:
+		recordWebhookFailurePolicies(obj, false)
+	}
 	return nil
 }
 
-func (r *GatekeeperReconciler) validateWebhookDeployment() (error, bool) {
+// validateWebhookDeployment returns whether the webhook deployment is pending
+// i.e. not all its replicas are ready, and the percentage of ready replicas.
+func (r *GatekeeperReconciler) validateWebhookDeployment(ctx context.Context) (err error, pending bool, readyPercent int32) {
+	ctx, span := tracing.StartSpan(ctx, "validateWebhookDeployment")
+	defer func() {
+		span.SetAttributes(attribute.Bool("pending", pending))
+		tracing.EndSpan(span, err)
+	}()
+
 	r.Log.Info(fmt.Sprintf("Validating %s deployment status", WebhookDeploymentName))
 
-	ctx := context.Background()
 	obj, err := util.GetManifestObject(WebhookFile)
 	if err != nil {
-		return err, false
+		return err, false, 0
 	}
 	deployment := &unstructured.Unstructured{}
 	deployment.SetAPIVersion(obj.GetAPIVersion())
 	deployment.SetKind(obj.GetKind())
 	namespacedName := types.NamespacedName{
-		Namespace: r.Namespace,
+		Namespace: r.gatekeeperNamespace(),
 		Name:      obj.GetName(),
 	}
 
@@ -321,32 +638,35 @@ func (r *GatekeeperReconciler) validateWebhookDeployment() (error, bool) {
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			r.Log.Info("Deployment not found, will set webhook failure policy to ignore and requeue...")
-			return nil, true
+			return nil, true, 0
 		}
-		return err, false
+		return err, false, 0
 	}
 	r.Log.Info("Deployment found, checking replicas ...")
 
 	replicas, ok, err := unstructured.NestedInt64(deployment.Object, "status", "replicas")
 	if err != nil {
-		return err, false
+		return err, false, 0
 	}
 
 	readyReplicas, ok, err := unstructured.NestedInt64(deployment.Object, "status", "readyReplicas")
 	if err != nil {
-		return err, false
+		return err, false, 0
 	}
 	if !ok {
 		r.Log.Info("Deployment status.readyReplicas not found or populated yet, will set webhook failure policy to ignore and requeue ...")
-		return nil, true
+		return nil, true, 0
 	}
 	if replicas == readyReplicas {
 		r.Log.Info("Deployment validation successful, all replicas ready", "replicas", replicas, "readyReplicas", readyReplicas)
-		return nil, false
+		return nil, false, 100
 	}
 	r.Log.Info("Deployment replicas not ready, will set webhook failure policy to ignore and requeue ...",
 		"replicas", replicas, "readyReplicas", readyReplicas)
-	return nil, true
+	if replicas == 0 || readyReplicas > replicas {
+		return nil, true, 100
+	}
+	return nil, true, int32(readyReplicas * 100 / replicas)
 }
 
 func getStaticAssets(gatekeeper *operatorv1alpha1.Gatekeeper) (deleteWebhookAssets, applyOrderedAssets, applyWebhookAssets, deleteCRDAssets []string) {
@@ -397,9 +717,22 @@ func getSubsetOfAssets(inputAssets []string, assetsToRemove ...string) []string
 	return outputAssets
 }
 
-func (r *GatekeeperReconciler) crudResource(obj *unstructured.Unstructured, gatekeeper *operatorv1alpha1.Gatekeeper, operation crudOperation) error {
+// crudResource applies or deletes the given object and records the
+// performed operation for the given asset name.
+func (r *GatekeeperReconciler) crudResource(ctx context.Context, asset string, obj *unstructured.Unstructured, gatekeeper *operatorv1alpha1.Gatekeeper, operation crudOperation) error {
+	ctx, span := tracing.StartSpan(ctx, "crudResource", attribute.String("asset", asset))
+	assetOperation, err := r.crudObject(ctx, obj, gatekeeper, operation)
+	if err != nil {
+		assetOperation = assetOperationError
+	}
+	recordAssetOperation(asset, assetOperation)
+	span.SetAttributes(attribute.String("operation", assetOperation))
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (r *GatekeeperReconciler) crudObject(ctx context.Context, obj *unstructured.Unstructured, gatekeeper *operatorv1alpha1.Gatekeeper, operation crudOperation) (string, error) {
 	var err error
-	ctx := context.Background()
 	clusterObj := &unstructured.Unstructured{}
 	clusterObj.SetAPIVersion(obj.GetAPIVersion())
 	clusterObj.SetKind(obj.GetKind())
@@ -417,7 +750,7 @@ func (r *GatekeeperReconciler) crudResource(obj *unstructured.Unstructured, gate
 	if obj.GetKind() != util.NamespaceKind {
 		err = ctrl.SetControllerReference(gatekeeper, obj, r.Scheme)
 		if err != nil {
-			return errors.Wrapf(err, "Unable to set controller reference for %s", namespacedName)
+			return "", errors.Wrapf(err, "Unable to set controller reference for %s", namespacedName)
 		}
 	}
 
@@ -428,47 +761,90 @@ func (r *GatekeeperReconciler) crudResource(obj *unstructured.Unstructured, gate
 		if operation == apply {
 			err = merge.RetainClusterObjectFields(obj, clusterObj)
 			if err != nil {
-				return errors.Wrapf(err, "Unable to retain cluster object fields from %s", namespacedName)
+				return "", errors.Wrapf(err, "Unable to retain cluster object fields from %s", namespacedName)
 			}
 
 			if err = r.Update(ctx, obj); err != nil {
-				return errors.Wrapf(err, "Error attempting to update resource %s", namespacedName)
+				return "", errors.Wrapf(err, "Error attempting to update resource %s", namespacedName)
 			}
 
 			logger.Info(fmt.Sprintf("Updated Gatekeeper resource"))
+			if objectChanged(clusterObj, obj) {
+				r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonUpdated, "Updated %s %s", obj.GetKind(), namespacedName)
+			}
+			return assetOperationUpdate, nil
 		} else if operation == delete {
 			if err = r.Delete(ctx, obj); err != nil {
-				return errors.Wrapf(err, "Error attempting to delete resource %s", namespacedName)
+				return "", errors.Wrapf(err, "Error attempting to delete resource %s", namespacedName)
 			}
 			logger.Info(fmt.Sprintf("Deleted Gatekeeper resource"))
+			r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonDeleted, "Deleted %s %s", obj.GetKind(), namespacedName)
+			return assetOperationDelete, nil
 		}
 
 	case apierrors.IsNotFound(err):
 		if operation == apply {
 			if err = r.Create(ctx, obj); err != nil {
-				return errors.Wrapf(err, "Error attempting to create resource %s", namespacedName)
+				return "", errors.Wrapf(err, "Error attempting to create resource %s", namespacedName)
 			}
 			logger.Info(fmt.Sprintf("Created Gatekeeper resource"))
+			r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonCreated, "Created %s %s", obj.GetKind(), namespacedName)
+			return assetOperationCreate, nil
 		}
 
 	case err != nil:
-		return errors.Wrapf(err, "Error attempting to get resource %s", namespacedName)
+		return "", errors.Wrapf(err, "Error attempting to get resource %s", namespacedName)
 	}
 
-	return nil
+	return assetOperationSkip, nil
+}
+
+// objectChanged returns whether the given cluster object was changed by an
+// update, ignoring the metadata written by the API server on every update and
+// the status, which is not managed by the operator.
+func objectChanged(before, after *unstructured.Unstructured) bool {
+	before, after = before.DeepCopy(), after.DeepCopy()
+	for _, obj := range []*unstructured.Unstructured{before, after} {
+		obj.SetResourceVersion("")
+		obj.SetManagedFields(nil)
+		unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(obj.Object, "status")
+	}
+	return !equality.Semantic.DeepEqual(before.Object, after.Object)
 }
 
 func (r *GatekeeperReconciler) isOpenShift() bool {
 	return r.PlatformInfo.IsOpenShift()
 }
 
+// removeAnnotation removes the given annotation from the Gatekeeper resource
+// once the request it holds has been handled.
+func (r *GatekeeperReconciler) removeAnnotation(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, key string) error {
+	patch := client.MergeFrom(gatekeeper.DeepCopy())
+	annotations := map[string]string{}
+	for k, v := range gatekeeper.GetAnnotations() {
+		if k != key {
+			annotations[k] = v
+		}
+	}
+	gatekeeper.SetAnnotations(annotations)
+	if err := r.Patch(ctx, gatekeeper, patch); err != nil {
+		return errors.Wrapf(err, "Unable to remove annotation %s", key)
+	}
+	return nil
+}
+
 var commonSpecOverridesFn = []func(*unstructured.Unstructured, operatorv1alpha1.GatekeeperSpec) error{
 	setAffinity,
 	setNodeSelector,
 	setPodAnnotations,
 	setTolerations,
+	setImagePullSecrets,
 	containerOverrides,
 	setEnableMutation,
+	setCertRotation,
+	setMetrics,
+	setProfiling,
 }
 
 var commonContainerOverridesFn = []func(map[string]interface{}, operatorv1alpha1.GatekeeperSpec) error{
@@ -477,8 +853,16 @@ var commonContainerOverridesFn = []func(map[string]interface{}, operatorv1alpha1
 
 // crOverrides
 func crOverrides(gatekeeper *operatorv1alpha1.Gatekeeper, asset string, obj *unstructured.Unstructured, namespace string, isOpenshift bool, controllerDeploymentPending bool) error {
+	setCommonMetadata(obj, gatekeeper.Spec)
 	if asset == NamespaceFile {
 		obj.SetName(namespace)
+		namespaceOverrides(obj, gatekeeper.Spec.NamespaceConfig)
+		if err := setHostNetworkPodSecurity(obj, gatekeeper.Spec); err != nil {
+			return err
+		}
+		if isOpenshift {
+			return setClusterMonitoringLabel(obj, gatekeeper.Spec.Monitoring)
+		}
 		return nil
 	}
 	// set resource's namespace
@@ -494,6 +878,16 @@ func crOverrides(gatekeeper *operatorv1alpha1.Gatekeeper, asset string, obj *uns
 		if err := auditOverrides(obj, gatekeeper.Spec.Audit); err != nil {
 			return err
 		}
+		var auditPodLabels map[string]string
+		if gatekeeper.Spec.Audit != nil {
+			auditPodLabels = gatekeeper.Spec.Audit.PodLabels
+		}
+		if err := setPodLabels(obj, gatekeeper.Spec.PodLabels, auditPodLabels); err != nil {
+			return err
+		}
+		if err := checkImageDigest(obj, gatekeeper.Spec.Image); err != nil {
+			return err
+		}
 		if isOpenshift {
 			if err := removeAnnotations(obj); err != nil {
 				return err
@@ -511,6 +905,16 @@ func crOverrides(gatekeeper *operatorv1alpha1.Gatekeeper, asset string, obj *uns
 		if err := webhookOverrides(obj, gatekeeper.Spec.Webhook); err != nil {
 			return err
 		}
+		var webhookPodLabels map[string]string
+		if gatekeeper.Spec.Webhook != nil {
+			webhookPodLabels = gatekeeper.Spec.Webhook.PodLabels
+		}
+		if err := setPodLabels(obj, gatekeeper.Spec.PodLabels, webhookPodLabels); err != nil {
+			return err
+		}
+		if err := checkImageDigest(obj, gatekeeper.Spec.Image); err != nil {
+			return err
+		}
 		if isOpenshift {
 			if err := removeAnnotations(obj); err != nil {
 				return err
@@ -537,6 +941,9 @@ func crOverrides(gatekeeper *operatorv1alpha1.Gatekeeper, asset string, obj *uns
 		); err != nil {
 			return err
 		}
+		if err := setClientConfigURL(obj, gatekeeper.Spec.Webhook); err != nil {
+			return err
+		}
 	// MutatingWebhookConfiguration overrides
 	case MutatingWebhookConfiguration:
 		if err := webhookConfigurationOverrides(
@@ -549,6 +956,24 @@ func crOverrides(gatekeeper *operatorv1alpha1.Gatekeeper, asset string, obj *uns
 		); err != nil {
 			return err
 		}
+		if err := setClientConfigURL(obj, gatekeeper.Spec.Webhook); err != nil {
+			return err
+		}
+	// Webhook Service overrides
+	case WebhookServiceFile:
+		if err := webhookServiceOverrides(obj, gatekeeper.Spec.Webhook); err != nil {
+			return err
+		}
+	// ServiceMonitor overrides
+	case ServiceMonitorFile:
+		if err := serviceMonitorOverrides(obj, gatekeeper.Spec.Monitoring); err != nil {
+			return err
+		}
+	// PrometheusRule and dashboard overrides
+	case PrometheusRuleFile, DashboardFile:
+		if gatekeeper.Spec.Monitoring != nil {
+			setMonitoringLabels(obj, gatekeeper.Spec.Monitoring.Labels)
+		}
 	// ClusterRole overrides
 	case ClusterRoleFile:
 		if !mutatingWebhookEnabled(gatekeeper.Spec.MutatingWebhook) {
@@ -560,6 +985,91 @@ func crOverrides(gatekeeper *operatorv1alpha1.Gatekeeper, asset string, obj *uns
 	return nil
 }
 
+// namespaceOverrides sets the Pod Security Admission labels and the
+// additional labels and annotations of the Gatekeeper namespace. The labels
+// exempting the namespace from Gatekeeper admission and marking it as the
+// Gatekeeper system namespace are kept as is.
+func namespaceOverrides(obj *unstructured.Unstructured, config *operatorv1alpha1.NamespaceConfig) {
+	if config == nil {
+		return
+	}
+	labels := mergeStringMaps(obj.GetLabels(), config.Labels)
+	if podSecurity := config.PodSecurity; podSecurity != nil {
+		setPodSecurityLabel(labels, "enforce", (*string)(podSecurity.Enforce), podSecurity.EnforceVersion)
+		setPodSecurityLabel(labels, "audit", (*string)(podSecurity.Audit), podSecurity.AuditVersion)
+		setPodSecurityLabel(labels, "warn", (*string)(podSecurity.Warn), podSecurity.WarnVersion)
+	}
+	for _, key := range []string{IgnoreLabel, SystemLabel} {
+		if value, ok := obj.GetLabels()[key]; ok {
+			labels[key] = value
+		}
+	}
+	obj.SetLabels(labels)
+	if len(config.Annotations) > 0 {
+		obj.SetAnnotations(mergeStringMaps(obj.GetAnnotations(), config.Annotations))
+	}
+}
+
+// setHostNetworkPodSecurity relaxes the enforced Pod Security Admission level
+// of the Gatekeeper namespace to privileged if the webhook runs with
+// hostNetwork, which even the baseline level forbids. An enforced level
+// explicitly configured otherwise is refused since all the webhook pods would
+// be rejected.
+func setHostNetworkPodSecurity(obj *unstructured.Unstructured, spec operatorv1alpha1.GatekeeperSpec) error {
+	if spec.Webhook == nil || spec.Webhook.HostNetwork == nil || !*spec.Webhook.HostNetwork {
+		return nil
+	}
+	if config := spec.NamespaceConfig; config != nil && config.PodSecurity != nil && config.PodSecurity.Enforce != nil &&
+		*config.PodSecurity.Enforce != operatorv1alpha1.PodSecurityPrivileged {
+		return fmt.Errorf("spec.webhook.hostNetwork requires the %s Pod Security level but spec.namespaceConfig.podSecurity.enforce is %s",
+			operatorv1alpha1.PodSecurityPrivileged, *config.PodSecurity.Enforce)
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[PodSecurityLabelPrefix+"enforce"] = string(operatorv1alpha1.PodSecurityPrivileged)
+	obj.SetLabels(labels)
+	return nil
+}
+
+func setPodSecurityLabel(labels map[string]string, mode string, level, version *string) {
+	if level != nil {
+		labels[PodSecurityLabelPrefix+mode] = *level
+	}
+	if version != nil {
+		labels[PodSecurityLabelPrefix+mode+"-version"] = *version
+	}
+}
+
+// setCommonMetadata sets the standard app.kubernetes.io labels, the common
+// labels and the common annotations on the given object. The labels and
+// annotations of the manifest take precedence since they may be relied upon,
+// e.g. by selectors.
+func setCommonMetadata(obj *unstructured.Unstructured, spec operatorv1alpha1.GatekeeperSpec) {
+	labels := map[string]string{
+		ManagedByLabel: managedByOperator,
+		PartOfLabel:    partOfGatekeeper,
+		VersionLabel:   manifestVersion(),
+	}
+	obj.SetLabels(mergeStringMaps(labels, spec.CommonLabels, obj.GetLabels()))
+	if annotations := mergeStringMaps(spec.CommonAnnotations, obj.GetAnnotations()); len(annotations) > 0 {
+		obj.SetAnnotations(annotations)
+	}
+}
+
+// mergeStringMaps returns the union of the given maps. The entries of later
+// maps take precedence.
+func mergeStringMaps(maps ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
 func commonOverrides(obj *unstructured.Unstructured, spec operatorv1alpha1.GatekeeperSpec) error {
 	for _, f := range commonSpecOverridesFn {
 		if err := f(obj, spec); err != nil {
@@ -574,6 +1084,9 @@ func auditOverrides(obj *unstructured.Unstructured, audit *operatorv1alpha1.Audi
 		if err := setReplicas(obj, audit.Replicas); err != nil {
 			return err
 		}
+		if err := setComponentImage(obj, audit.Image); err != nil {
+			return err
+		}
 		if err := setLogLevel(obj, audit.LogLevel); err != nil {
 			return err
 		}
@@ -604,6 +1117,9 @@ func webhookOverrides(obj *unstructured.Unstructured, webhook *operatorv1alpha1.
 		if err := setReplicas(obj, webhook.Replicas); err != nil {
 			return err
 		}
+		if err := setComponentImage(obj, webhook.Image); err != nil {
+			return err
+		}
 		if err := setLogLevel(obj, webhook.LogLevel); err != nil {
 			return err
 		}
@@ -616,6 +1132,62 @@ func webhookOverrides(obj *unstructured.Unstructured, webhook *operatorv1alpha1.
 		if err := setDisabledBuiltins(obj, webhook.DisabledBuiltins); err != nil {
 			return err
 		}
+		if err := setHostNetwork(obj, webhook.HostNetwork, webhook.DNSPolicy); err != nil {
+			return err
+		}
+		if err := setWebhookPort(obj, webhook.Port); err != nil {
+			return err
+		}
+		if err := setHealthPort(obj, webhook.HealthPort); err != nil {
+			return err
+		}
+		if err := setMetricsPort(obj, webhook.MetricsPort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func webhookServiceOverrides(obj *unstructured.Unstructured, webhook *operatorv1alpha1.WebhookConfig) error {
+	if webhook != nil {
+		if err := setServiceTargetPort(obj, webhook.Port); err != nil {
+			return err
+		}
+		if err := serviceOverrides(obj, webhook.Service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func serviceOverrides(obj *unstructured.Unstructured, service *operatorv1alpha1.ServiceConfig) error {
+	if service == nil {
+		return nil
+	}
+	if service.Annotations != nil {
+		obj.SetAnnotations(mergeStringMaps(obj.GetAnnotations(), service.Annotations))
+	}
+	if service.Labels != nil {
+		obj.SetLabels(mergeStringMaps(obj.GetLabels(), service.Labels))
+	}
+	if service.InternalTrafficPolicy != nil {
+		if err := unstructured.SetNestedField(obj.Object, string(*service.InternalTrafficPolicy), "spec", "internalTrafficPolicy"); err != nil {
+			return errors.Wrapf(err, "Failed to set service internalTrafficPolicy")
+		}
+	}
+	if service.IPFamilies != nil {
+		ipFamilies := make([]string, len(service.IPFamilies))
+		for i, f := range service.IPFamilies {
+			ipFamilies[i] = string(f)
+		}
+		if err := unstructured.SetNestedStringSlice(obj.Object, ipFamilies, "spec", "ipFamilies"); err != nil {
+			return errors.Wrapf(err, "Failed to set service ipFamilies")
+		}
+	}
+	if service.IPFamilyPolicy != nil {
+		if err := unstructured.SetNestedField(obj.Object, string(*service.IPFamilyPolicy), "spec", "ipFamilyPolicy"); err != nil {
+			return errors.Wrapf(err, "Failed to set service ipFamilyPolicy")
+		}
 	}
 	return nil
 }
@@ -862,6 +1434,139 @@ func setEnableMutation(obj *unstructured.Unstructured, spec operatorv1alpha1.Gat
 	}
 }
 
+// setCertRotation disables Gatekeeper's certificate rotation when the
+// operator manages the webhook serving certificates. Otherwise Gatekeeper
+// would replace them with certificates only valid for the in-cluster Service.
+func setCertRotation(obj *unstructured.Unstructured, spec operatorv1alpha1.GatekeeperSpec) error {
+	if webhookURLModeEnabled(spec.Webhook) {
+		return setContainerArg(obj, managerContainer, DisableCertRotationArg, "true", false)
+	}
+	return nil
+}
+
+func setHostNetwork(obj *unstructured.Unstructured, hostNetwork *bool, dnsPolicy *corev1.DNSPolicy) error {
+	if hostNetwork != nil {
+		if err := unstructured.SetNestedField(obj.Object, *hostNetwork, "spec", "template", "spec", "hostNetwork"); err != nil {
+			return errors.Wrapf(err, "Failed to set hostNetwork")
+		}
+		// Pods running with hostNetwork need this DNS policy to resolve
+		// cluster Services.
+		if *hostNetwork && dnsPolicy == nil {
+			withHostNet := corev1.DNSClusterFirstWithHostNet
+			dnsPolicy = &withHostNet
+		}
+	}
+	if dnsPolicy != nil {
+		if err := unstructured.SetNestedField(obj.Object, string(*dnsPolicy), "spec", "template", "spec", "dnsPolicy"); err != nil {
+			return errors.Wrapf(err, "Failed to set dnsPolicy")
+		}
+	}
+	return nil
+}
+
+func setWebhookPort(obj *unstructured.Unstructured, port *int32) error {
+	if port != nil {
+		if err := setContainerArg(obj, managerContainer, PortArg, strconv.Itoa(int(*port)), false); err != nil {
+			return err
+		}
+		return setContainerPort(obj, webhookServerPortName, *port)
+	}
+	return nil
+}
+
+func setHealthPort(obj *unstructured.Unstructured, port *int32) error {
+	if port != nil {
+		if err := setContainerArg(obj, managerContainer, HealthAddrArg, fmt.Sprintf(":%d", *port), false); err != nil {
+			return err
+		}
+		if err := setContainerPort(obj, healthzPortName, *port); err != nil {
+			return err
+		}
+		return setContainerAttrWithFn(obj, managerContainer, func(container map[string]interface{}) error {
+			for _, probe := range []string{"livenessProbe", "readinessProbe"} {
+				if _, found, _ := unstructured.NestedMap(container, probe, "httpGet"); !found {
+					continue
+				}
+				if err := unstructured.SetNestedField(container, int64(*port), probe, "httpGet", "port"); err != nil {
+					return errors.Wrapf(err, "Failed to set container %s port", probe)
+				}
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+func setMetrics(obj *unstructured.Unstructured, spec operatorv1alpha1.GatekeeperSpec) error {
+	metrics := spec.Metrics
+	if metrics == nil {
+		return nil
+	}
+	// The OTLP receiver would be silently ignored by Gatekeeper without the
+	// opentelemetry backend.
+	if metrics.OTLP != nil {
+		found := false
+		for _, backend := range metrics.Backends {
+			found = found || backend == operatorv1alpha1.MetricsBackendOpenTelemetry
+		}
+		if !found {
+			return fmt.Errorf("spec.metrics.otlp requires the %s backend in spec.metrics.backends", operatorv1alpha1.MetricsBackendOpenTelemetry)
+		}
+	}
+	for _, backend := range metrics.Backends {
+		if err := setContainerArg(obj, managerContainer, MetricsBackendArg, string(backend), true); err != nil {
+			return err
+		}
+	}
+	if err := setMetricsPort(obj, metrics.PrometheusPort); err != nil {
+		return err
+	}
+	if metrics.OTLP != nil {
+		if err := setContainerArg(obj, managerContainer, OTLPEndpointArg, metrics.OTLP.Endpoint, false); err != nil {
+			return err
+		}
+		if metrics.OTLP.Insecure != nil {
+			if err := setContainerEnv(obj, managerContainer, OTLPInsecureEnvVar, strconv.FormatBool(*metrics.OTLP.Insecure)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func setMetricsPort(obj *unstructured.Unstructured, port *int32) error {
+	if port != nil {
+		if err := setContainerArg(obj, managerContainer, PrometheusPortArg, strconv.Itoa(int(*port)), false); err != nil {
+			return err
+		}
+		return setContainerPort(obj, metricsPortName, *port)
+	}
+	return nil
+}
+
+func setServiceTargetPort(obj *unstructured.Unstructured, port *int32) error {
+	if port == nil {
+		return nil
+	}
+	ports, found, err := unstructured.NestedSlice(obj.Object, "spec", "ports")
+	if err != nil || !found {
+		return errors.Wrapf(err, "Failed to retrieve service ports")
+	}
+	for _, p := range ports {
+		servicePort := p.(map[string]interface{})
+		if servicePort["name"] != WebhookServicePortName {
+			continue
+		}
+		if err := unstructured.SetNestedField(servicePort, int64(*port), "targetPort"); err != nil {
+			return errors.Wrapf(err, "Failed to set service targetPort")
+		}
+	}
+	if err := unstructured.SetNestedSlice(obj.Object, ports, "spec", "ports"); err != nil {
+		return errors.Wrapf(err, "Failed to set service ports")
+	}
+	return nil
+}
+
 func setWebhookConfigurationWithFn(obj *unstructured.Unstructured, webhookName string, webhookFn func(map[string]interface{}) error) error {
 	webhooks, found, err := unstructured.NestedSlice(obj.Object, "webhooks")
 	if err != nil || !found {
@@ -992,6 +1697,41 @@ func setPodAnnotations(obj *unstructured.Unstructured, spec operatorv1alpha1.Gat
 	return nil
 }
 
+// setPodLabels merges the shared and component pod labels into the labels of
+// the pod template. The labels selected by the Deployment are kept as is since
+// its selector is immutable and must keep matching its pods.
+func setPodLabels(obj *unstructured.Unstructured, sharedLabels, componentLabels map[string]string) error {
+	if len(sharedLabels) == 0 && len(componentLabels) == 0 {
+		return nil
+	}
+	podLabels, _, err := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to retrieve pod labels")
+	}
+	selectorLabels, _, err := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to retrieve selector labels")
+	}
+	labels := mergeStringMaps(podLabels, sharedLabels, componentLabels, selectorLabels)
+	if err := unstructured.SetNestedStringMap(obj.Object, labels, "spec", "template", "metadata", "labels"); err != nil {
+		return errors.Wrapf(err, "Failed to set podLabels")
+	}
+	return nil
+}
+
+func setImagePullSecrets(obj *unstructured.Unstructured, spec operatorv1alpha1.GatekeeperSpec) error {
+	if spec.Image != nil && spec.Image.ImagePullSecrets != nil {
+		secrets := make([]interface{}, len(spec.Image.ImagePullSecrets))
+		for i, secret := range spec.Image.ImagePullSecrets {
+			secrets[i] = util.ToMap(secret)
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, secrets, "spec", "template", "spec", "imagePullSecrets"); err != nil {
+			return errors.Wrapf(err, "Failed to set imagePullSecrets")
+		}
+	}
+	return nil
+}
+
 func setTolerations(obj *unstructured.Unstructured, spec operatorv1alpha1.GatekeeperSpec) error {
 	if spec.Tolerations != nil {
 		tolerations := make([]interface{}, len(spec.Tolerations))
@@ -1007,6 +1747,20 @@ func setTolerations(obj *unstructured.Unstructured, spec operatorv1alpha1.Gateke
 
 // Container specific setters
 
+// setComponentImage overrides the Gatekeeper image of the manager container
+// with the image of the component.
+func setComponentImage(obj *unstructured.Unstructured, image *string) error {
+	if image != nil {
+		return setContainerAttrWithFn(obj, managerContainer, func(container map[string]interface{}) error {
+			if err := unstructured.SetNestedField(container, *image, "image"); err != nil {
+				return errors.Wrapf(err, "Failed to set container image")
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
 func setResources(obj *unstructured.Unstructured, resources *corev1.ResourceRequirements) error {
 	if resources != nil {
 		return setContainerAttrWithFn(obj, managerContainer, func(container map[string]interface{}) error {
@@ -1032,6 +1786,15 @@ func setImage(container map[string]interface{}, spec operatorv1alpha1.Gatekeeper
 	if spec.Image == nil {
 		return nil
 	}
+	if spec.Image.ImageRegistry != nil {
+		image, _, err := unstructured.NestedString(container, "image")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to retrieve container image")
+		}
+		if err := unstructured.SetNestedField(container, replaceImageRegistry(image, *spec.Image.ImageRegistry), "image"); err != nil {
+			return errors.Wrapf(err, "Failed to set container image")
+		}
+	}
 	if spec.Image.ImagePullPolicy != nil {
 		if err := unstructured.SetNestedField(container, string(*spec.Image.ImagePullPolicy), "imagePullPolicy"); err != nil {
 			return errors.Wrapf(err, "Failed to set container image pull policy")
@@ -1040,6 +1803,21 @@ func setImage(container map[string]interface{}, spec operatorv1alpha1.Gatekeeper
 	return nil
 }
 
+// replaceImageRegistry replaces the registry of the given image reference
+// with the given registry. References without a registry, e.g.
+// openpolicyagent/gatekeeper:v3.11.1, are pulled from Docker Hub, whose
+// registry is implied.
+func replaceImageRegistry(image, registry string) string {
+	name := image
+	if i := strings.Index(image, "/"); i >= 0 {
+		host := image[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			name = image[i+1:]
+		}
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + name
+}
+
 func setContainerAttrWithFn(obj *unstructured.Unstructured, containerName string, containerFn func(map[string]interface{}) error) error {
 	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
 	if err != nil || !found {
@@ -1061,6 +1839,24 @@ func setContainerAttrWithFn(obj *unstructured.Unstructured, containerName string
 	return nil
 }
 
+func setContainerPort(obj *unstructured.Unstructured, portName string, port int32) error {
+	return setContainerAttrWithFn(obj, managerContainer, func(container map[string]interface{}) error {
+		ports, found, err := unstructured.NestedSlice(container, "ports")
+		if !found || err != nil {
+			return errors.Wrapf(err, "Unable to retrieve container ports for: %s", managerContainer)
+		}
+		for _, p := range ports {
+			containerPort := p.(map[string]interface{})
+			if containerPort["name"] == portName {
+				if err := unstructured.SetNestedField(containerPort, int64(port), "containerPort"); err != nil {
+					return errors.Wrapf(err, "Failed to set container port %s", portName)
+				}
+			}
+		}
+		return unstructured.SetNestedSlice(container, ports, "ports")
+	})
+}
+
 func setContainerArg(obj *unstructured.Unstructured, containerName, argName string, argValue string, isMultiArg bool) error {
 	return setContainerAttrWithFn(obj, containerName, func(container map[string]interface{}) error {
 		args, found, err := unstructured.NestedStringSlice(container, "args")
@@ -1082,6 +1878,33 @@ func setContainerArg(obj *unstructured.Unstructured, containerName, argName stri
 	})
 }
 
+func setContainerEnv(obj *unstructured.Unstructured, containerName, name, value string) error {
+	return setContainerAttrWithFn(obj, containerName, func(container map[string]interface{}) error {
+		env, _, err := unstructured.NestedSlice(container, "env")
+		if err != nil {
+			return errors.Wrapf(err, "Unable to retrieve container environment for: %s", containerName)
+		}
+		exists := false
+		for i, e := range env {
+			envVar := e.(map[string]interface{})
+			if envVar["name"] == name {
+				env[i] = map[string]interface{}{
+					"name":  name,
+					"value": value,
+				}
+				exists = true
+			}
+		}
+		if !exists {
+			env = append(env, map[string]interface{}{
+				"name":  name,
+				"value": value,
+			})
+		}
+		return unstructured.SetNestedSlice(container, env, "env")
+	})
+}
+
 func unsetContainerArg(obj *unstructured.Unstructured, containerName, argName string, argValue string, isMultiArg bool) error {
 	return setContainerAttrWithFn(obj, containerName, func(container map[string]interface{}) error {
 		args, found, err := unstructured.NestedStringSlice(container, "args")
@@ -1141,6 +1964,38 @@ func setClientConfigNamespace(obj *unstructured.Unstructured, asset, namespace s
 	return nil
 }
 
+// setClientConfigURL replaces each webhook's clientConfig.service with a
+// clientConfig.url pointing at the externally reachable webhook endpoint.
+func setClientConfigURL(obj *unstructured.Unstructured, webhook *operatorv1alpha1.WebhookConfig) error {
+	if !webhookURLModeEnabled(webhook) {
+		return nil
+	}
+	urlConfig := webhook.ClientConfig.URL
+	if urlConfig == nil {
+		return fmt.Errorf("spec.webhook.clientConfig.url must be set when mode is %s", operatorv1alpha1.WebhookClientConfigURL)
+	}
+
+	webhooks, found, err := unstructured.NestedSlice(obj.Object, "webhooks")
+	if err != nil || !found {
+		return errors.Wrapf(err, "Failed to retrieve webhooks definition")
+	}
+	for _, w := range webhooks {
+		webhook := w.(map[string]interface{})
+		path, _, err := unstructured.NestedString(webhook, "clientConfig", "service", "path")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to retrieve webhook clientConfig.service.path")
+		}
+		unstructured.RemoveNestedField(webhook, "clientConfig", "service")
+		if err := unstructured.SetNestedField(webhook, webhookURL(urlConfig, path), "clientConfig", "url"); err != nil {
+			return errors.Wrapf(err, "Failed to set webhook clientConfig.url")
+		}
+	}
+	if err := unstructured.SetNestedSlice(obj.Object, webhooks, "webhooks"); err != nil {
+		return errors.Wrapf(err, "Failed to set webhooks")
+	}
+	return nil
+}
+
 func setControllerManagerExceptNamespace(obj *unstructured.Unstructured, asset, namespace string) error {
 	if asset != WebhookFile {
 		return nil
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 
 	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
 	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
@@ -181,6 +182,133 @@ func TestCustomNamespace(t *testing.T) {
 	}
 }
 
+func TestWebhookClientConfigURL(t *testing.T) {
+	g := NewWithT(t)
+	urlMode := operatorv1alpha1.WebhookClientConfigURL
+	port := int32(8443)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	webhookConfigs := map[string]map[string]string{
+		ValidatingWebhookConfiguration: {
+			ValidationGatekeeperWebhook:       "https://gatekeeper.example.com:8443/v1/admit",
+			CheckIgnoreLabelGatekeeperWebhook: "https://gatekeeper.example.com:8443/v1/admitlabel",
+		},
+		MutatingWebhookConfiguration: {
+			MutationGatekeeperWebhook: "https://gatekeeper.example.com:8443/v1/mutate",
+		},
+	}
+
+	// test default
+	for webhookConfigFile := range webhookConfigs {
+		webhookConfig, err := util.GetManifestObject(webhookConfigFile)
+		g.Expect(err).ToNot(HaveOccurred())
+		err = crOverrides(gatekeeper, webhookConfigFile, webhookConfig, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		assertWebhooksWithFn(g, webhookConfig, func(webhook map[string]interface{}) {
+			_, found, err := unstructured.NestedString(webhook, "clientConfig", "url")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(found).To(BeFalse())
+			name, found, err := unstructured.NestedString(webhook, "clientConfig", "service", "name")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(found).To(BeTrue())
+			g.Expect(name).To(Equal(WebhookServiceName))
+		})
+	}
+	for _, asset := range []string{AuditFile, WebhookFile} {
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		err = crOverrides(gatekeeper, asset, obj, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		expectObjContainerArgument(g, managerContainer, obj).NotTo(HaveKey(DisableCertRotationArg))
+	}
+
+	// test URL mode without a URL
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		ClientConfig: &operatorv1alpha1.WebhookClientConfig{
+			Mode: &urlMode,
+		},
+	}
+	valObj, err := util.GetManifestObject(ValidatingWebhookConfiguration)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, ValidatingWebhookConfiguration, valObj, namespace, false, false)
+	g.Expect(err).To(HaveOccurred())
+
+	// test URL mode
+	gatekeeper.Spec.Webhook.ClientConfig.URL = &operatorv1alpha1.WebhookURLConfig{
+		Host: "gatekeeper.example.com",
+		Port: &port,
+	}
+	for webhookConfigFile, expectedURLs := range webhookConfigs {
+		webhookConfig, err := util.GetManifestObject(webhookConfigFile)
+		g.Expect(err).ToNot(HaveOccurred())
+		err = crOverrides(gatekeeper, webhookConfigFile, webhookConfig, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		assertWebhooksWithFn(g, webhookConfig, func(webhook map[string]interface{}) {
+			url, found, err := unstructured.NestedString(webhook, "clientConfig", "url")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(found).To(BeTrue())
+			g.Expect(url).To(Equal(expectedURLs[webhook["name"].(string)]))
+			_, found, err = unstructured.NestedMap(webhook, "clientConfig", "service")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(found).To(BeFalse())
+		})
+	}
+	for _, asset := range []string{AuditFile, WebhookFile} {
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		err = crOverrides(gatekeeper, asset, obj, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		expectObjContainerArgument(g, managerContainer, obj).To(HaveKeyWithValue(DisableCertRotationArg, "true"))
+	}
+}
+
+func TestWebhookExposeMode(t *testing.T) {
+	g := NewWithT(t)
+	urlMode := operatorv1alpha1.WebhookClientConfigURL
+	serviceMode := operatorv1alpha1.WebhookClientConfigService
+	loadBalancer := operatorv1alpha1.WebhookExposeLoadBalancer
+	webhook := &operatorv1alpha1.WebhookConfig{}
+
+	// test default
+	g.Expect(webhookExposeMode(nil)).To(Equal(operatorv1alpha1.WebhookExposeNone))
+	g.Expect(webhookExposeMode(webhook)).To(Equal(operatorv1alpha1.WebhookExposeNone))
+
+	// test expose mode is ignored in Service mode
+	webhook.ClientConfig = &operatorv1alpha1.WebhookClientConfig{
+		Mode: &serviceMode,
+		URL: &operatorv1alpha1.WebhookURLConfig{
+			Host:   "gatekeeper.example.com",
+			Expose: &loadBalancer,
+		},
+	}
+	g.Expect(webhookExposeMode(webhook)).To(Equal(operatorv1alpha1.WebhookExposeNone))
+
+	// test URL mode
+	webhook.ClientConfig.Mode = &urlMode
+	g.Expect(webhookExposeMode(webhook)).To(Equal(operatorv1alpha1.WebhookExposeLoadBalancer))
+
+	service := webhookLoadBalancerService(namespace, webhookURLPort(webhook.ClientConfig.URL), webhook.ClientConfig.URL.Host)
+	g.Expect(service.GetNamespace()).To(Equal(namespace))
+	serviceType, _, err := unstructured.NestedString(service.Object, "spec", "type")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serviceType).To(Equal("LoadBalancer"))
+	ports, _, err := unstructured.NestedSlice(service.Object, "spec", "ports")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ports).To(HaveLen(1))
+	g.Expect(ports[0]).To(HaveKeyWithValue("port", int64(defaultWebhookURLPort)))
+
+	route := webhookRoute(namespace, webhook.ClientConfig.URL.Host)
+	host, _, err := unstructured.NestedString(route.Object, "spec", "host")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(host).To(Equal("gatekeeper.example.com"))
+	termination, _, err := unstructured.NestedString(route.Object, "spec", "tls", "termination")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(termination).To(Equal("passthrough"))
+}
+
 func TestReplicas(t *testing.T) {
 	g := NewWithT(t)
 	auditReplicaOverride := int32(4)
@@ -449,6 +577,179 @@ func TestPodAnnotations(t *testing.T) {
 	assertPodAnnotations(g, webhookObj, podAnnotations)
 }
 
+func TestPodLabels(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test default pod labels
+	for _, asset := range []string{AuditFile, WebhookFile} {
+		manifest, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		expected, _, err := unstructured.NestedStringMap(manifest.Object, "spec", "template", "metadata", "labels")
+		g.Expect(err).ToNot(HaveOccurred())
+		obj := manifest.DeepCopy()
+		g.Expect(crOverrides(gatekeeper, asset, obj, namespace, false, false)).To(Succeed())
+		current, _, err := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(current).To(Equal(expected), asset)
+	}
+
+	// test shared and component pod labels
+	gatekeeper.Spec.PodLabels = map[string]string{
+		"sidecar.istio.io/inject": "false",
+		"cost-center":             "security",
+		"control-plane":           "shared",
+	}
+	gatekeeper.Spec.Audit = &operatorv1alpha1.AuditConfig{
+		PodLabels: map[string]string{
+			"cost-center": "audit",
+		},
+	}
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		PodLabels: map[string]string{
+			"gatekeeper.sh/operation": "audit",
+		},
+	}
+	for asset, costCenter := range map[string]string{AuditFile: "audit", WebhookFile: "security"} {
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		selector, _, err := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(crOverrides(gatekeeper, asset, obj, namespace, false, false)).To(Succeed())
+		current, _, err := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(current).To(HaveKeyWithValue("sidecar.istio.io/inject", "false"), asset)
+		g.Expect(current).To(HaveKeyWithValue("cost-center", costCenter), asset)
+		for k, v := range selector {
+			g.Expect(current).To(HaveKeyWithValue(k, v), asset)
+		}
+		currentSelector, _, err := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(currentSelector).To(Equal(selector), asset)
+	}
+}
+
+func TestNamespaceOverrides(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test default
+	manifest, err := util.GetManifestObject(NamespaceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	obj := manifest.DeepCopy()
+	g.Expect(crOverrides(gatekeeper, NamespaceFile, obj, namespace, false, false)).To(Succeed())
+	g.Expect(obj.GetName()).To(Equal(namespace))
+	for k, v := range manifest.GetLabels() {
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue(k, v))
+	}
+	g.Expect(obj.GetAnnotations()).To(BeEmpty())
+
+	// test pod security, labels and annotations
+	baseline := operatorv1alpha1.PodSecurityBaseline
+	privileged := operatorv1alpha1.PodSecurityPrivileged
+	version := "v1.27"
+	gatekeeper.Spec.NamespaceConfig = &operatorv1alpha1.NamespaceConfig{
+		PodSecurity: &operatorv1alpha1.PodSecurityConfig{
+			Enforce:        &baseline,
+			EnforceVersion: &version,
+			Warn:           &privileged,
+		},
+		Labels: map[string]string{
+			"monitoring":                         "enabled",
+			IgnoreLabel:                          "yes",
+			"pod-security.kubernetes.io/enforce": "restricted",
+		},
+		Annotations: map[string]string{
+			"scheduler.alpha.kubernetes.io/node-selector": "role=infra",
+		},
+	}
+	obj = manifest.DeepCopy()
+	g.Expect(crOverrides(gatekeeper, NamespaceFile, obj, namespace, false, false)).To(Succeed())
+	labels := obj.GetLabels()
+	g.Expect(labels).To(HaveKeyWithValue("pod-security.kubernetes.io/enforce", "baseline"))
+	g.Expect(labels).To(HaveKeyWithValue("pod-security.kubernetes.io/enforce-version", "v1.27"))
+	g.Expect(labels).To(HaveKeyWithValue("pod-security.kubernetes.io/warn", "privileged"))
+	g.Expect(labels).To(HaveKeyWithValue("pod-security.kubernetes.io/warn-version", "latest"))
+	g.Expect(labels).To(HaveKeyWithValue("pod-security.kubernetes.io/audit", "restricted"))
+	g.Expect(labels).To(HaveKeyWithValue("monitoring", "enabled"))
+	g.Expect(labels).To(HaveKeyWithValue(IgnoreLabel, manifest.GetLabels()[IgnoreLabel]))
+	g.Expect(labels).To(HaveKeyWithValue(SystemLabel, "yes"))
+	g.Expect(obj.GetAnnotations()).To(HaveKeyWithValue("scheduler.alpha.kubernetes.io/node-selector", "role=infra"))
+
+	// test hostNetwork refused with an enforced level other than privileged
+	hostNetwork := true
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		HostNetwork: &hostNetwork,
+	}
+	obj = manifest.DeepCopy()
+	g.Expect(crOverrides(gatekeeper, NamespaceFile, obj, namespace, false, false)).To(MatchError(ContainSubstring("requires the privileged Pod Security level")))
+
+	// test hostNetwork relaxes the enforced level
+	gatekeeper.Spec.NamespaceConfig = nil
+	obj = manifest.DeepCopy()
+	g.Expect(crOverrides(gatekeeper, NamespaceFile, obj, namespace, false, false)).To(Succeed())
+	labels = obj.GetLabels()
+	g.Expect(labels).To(HaveKeyWithValue("pod-security.kubernetes.io/enforce", "privileged"))
+	g.Expect(labels).To(HaveKeyWithValue("pod-security.kubernetes.io/audit", "restricted"))
+	g.Expect(labels).To(HaveKeyWithValue("pod-security.kubernetes.io/warn", "restricted"))
+}
+
+func TestCommonMetadata(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test standard labels by default
+	for _, asset := range []string{NamespaceFile, ClusterRoleFile, WebhookFile} {
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(crOverrides(gatekeeper, asset, obj, namespace, false, false)).To(Succeed())
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue(ManagedByLabel, managedByOperator), asset)
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue(PartOfLabel, partOfGatekeeper), asset)
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue(VersionLabel, manifestVersion()), asset)
+	}
+
+	// test common labels and annotations override the standard labels but
+	// not the manifest labels
+	gatekeeper.Spec.CommonLabels = map[string]string{
+		"cost-center":          "security",
+		PartOfLabel:            "platform",
+		"gatekeeper.sh/system": "no",
+	}
+	gatekeeper.Spec.CommonAnnotations = map[string]string{
+		"backup.example.com/include": "true",
+	}
+	for _, asset := range []string{NamespaceFile, WebhookServiceFile, WebhookFile} {
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(crOverrides(gatekeeper, asset, obj, namespace, false, false)).To(Succeed())
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue("cost-center", "security"), asset)
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue(PartOfLabel, "platform"), asset)
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue(ManagedByLabel, managedByOperator), asset)
+		g.Expect(obj.GetAnnotations()).To(HaveKeyWithValue("backup.example.com/include", "true"), asset)
+		if asset == WebhookServiceFile {
+			g.Expect(obj.GetLabels()).To(HaveKeyWithValue("gatekeeper.sh/system", "yes"), asset)
+		}
+	}
+
+	// test the pod template is left alone
+	obj, err := util.GetManifestObject(AuditFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(crOverrides(gatekeeper, AuditFile, obj, namespace, false, false)).To(Succeed())
+	podLabels, _, err := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(podLabels).ToNot(HaveKey("cost-center"))
+	assertPodAnnotations(g, obj, nil)
+}
+
 func assertPodAnnotations(g *WithT, obj *unstructured.Unstructured, expected map[string]string) {
 	g.Expect(obj).NotTo(BeNil())
 	current, found, err := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
@@ -698,6 +999,82 @@ func getDefaultImageConfig(g *WithT, obj *unstructured.Unstructured) (image stri
 	return image, imagePullPolicy
 }
 
+func TestImageRegistry(t *testing.T) {
+	g := NewWithT(t)
+	registry := "mirror.example.com:5000/gatekeeper"
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Image: &operatorv1alpha1.ImageConfig{
+				ImageRegistry: &registry,
+			},
+		},
+	}
+
+	// test registry override of the default image
+	t.Setenv(GatekeeperImageEnvVar, "")
+	webhookObj, err := util.GetManifestObject(WebhookFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	defaultImage, _ := getDefaultImageConfig(g, webhookObj)
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	image, _ := getDefaultImageConfig(g, webhookObj)
+	g.Expect(image).To(Equal(registry + "/" + defaultImage))
+
+	// test registry override of the related image
+	t.Setenv(GatekeeperImageEnvVar, "quay.io/gatekeeper/gatekeeper@sha256:0123")
+	auditObj, err := util.GetManifestObject(AuditFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, AuditFile, auditObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	image, _ = getDefaultImageConfig(g, auditObj)
+	g.Expect(image).To(Equal(registry + "/gatekeeper/gatekeeper@sha256:0123"))
+}
+
+func TestReplaceImageRegistry(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(replaceImageRegistry("openpolicyagent/gatekeeper:v3.11.1", "mirror.example.com")).To(Equal("mirror.example.com/openpolicyagent/gatekeeper:v3.11.1"))
+	g.Expect(replaceImageRegistry("gatekeeper:v3.11.1", "mirror.example.com/")).To(Equal("mirror.example.com/gatekeeper:v3.11.1"))
+	g.Expect(replaceImageRegistry("quay.io/gatekeeper/gatekeeper:v3.11.1", "mirror.example.com")).To(Equal("mirror.example.com/gatekeeper/gatekeeper:v3.11.1"))
+	g.Expect(replaceImageRegistry("localhost/gatekeeper:v3.11.1", "mirror.example.com")).To(Equal("mirror.example.com/gatekeeper:v3.11.1"))
+	g.Expect(replaceImageRegistry("registry:5000/gatekeeper:v3.11.1", "mirror.example.com")).To(Equal("mirror.example.com/gatekeeper:v3.11.1"))
+}
+
+func TestImagePullSecrets(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+
+	// test default imagePullSecrets
+	auditObj, err := util.GetManifestObject(AuditFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, AuditFile, auditObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, found, err := unstructured.NestedSlice(auditObj.Object, "spec", "template", "spec", "imagePullSecrets")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeFalse())
+
+	// test imagePullSecrets override
+	gatekeeper.Spec.Image = &operatorv1alpha1.ImageConfig{
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "mirror-credentials"}},
+	}
+	for _, asset := range []string{AuditFile, WebhookFile} {
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		err = crOverrides(gatekeeper, asset, obj, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		secrets, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "imagePullSecrets")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(found).To(BeTrue())
+		g.Expect(secrets).To(Equal([]interface{}{map[string]interface{}{"name": "mirror-credentials"}}))
+	}
+}
+
 func TestFailurePolicy(t *testing.T) {
 	g := NewWithT(t)
 
@@ -1278,6 +1655,309 @@ func TestAllWebhookArgs(t *testing.T) {
 	expectObjContainerArgument(g, managerContainer, webhookObj).To(HaveKeyWithValue(OperationArg, OperationMutationWebhook))
 }
 
+func TestHostNetwork(t *testing.T) {
+	g := NewWithT(t)
+	hostNetwork := true
+	dnsPolicy := corev1.DNSDefault
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test default
+	webhookObj, err := util.GetManifestObject(WebhookFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, found, err := unstructured.NestedBool(webhookObj.Object, "spec", "template", "spec", "hostNetwork")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeFalse())
+	_, found, err = unstructured.NestedString(webhookObj.Object, "spec", "template", "spec", "dnsPolicy")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeFalse())
+
+	// test hostNetwork defaults dnsPolicy
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		HostNetwork: &hostNetwork,
+	}
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	current, found, err := unstructured.NestedBool(webhookObj.Object, "spec", "template", "spec", "hostNetwork")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(current).To(BeTrue())
+	currentDNSPolicy, found, err := unstructured.NestedString(webhookObj.Object, "spec", "template", "spec", "dnsPolicy")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(currentDNSPolicy).To(BeEquivalentTo(corev1.DNSClusterFirstWithHostNet))
+
+	// test dnsPolicy override
+	gatekeeper.Spec.Webhook.DNSPolicy = &dnsPolicy
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	currentDNSPolicy, found, err = unstructured.NestedString(webhookObj.Object, "spec", "template", "spec", "dnsPolicy")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(currentDNSPolicy).To(BeEquivalentTo(dnsPolicy))
+}
+
+func TestWebhookPorts(t *testing.T) {
+	g := NewWithT(t)
+	port := int32(9443)
+	healthPort := int32(9091)
+	metricsPort := int32(9888)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test default
+	webhookObj, err := util.GetManifestObject(WebhookFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	serviceObj, err := util.GetManifestObject(WebhookServiceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, WebhookServiceFile, serviceObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	assertWebhookPorts(g, webhookObj, serviceObj, 8443, 9090, 8888, "webhook-server")
+
+	// test override
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		Port:        &port,
+		HealthPort:  &healthPort,
+		MetricsPort: &metricsPort,
+	}
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, WebhookServiceFile, serviceObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	expectObjContainerArgument(g, managerContainer, webhookObj).To(HaveKeyWithValue(PortArg, "9443"))
+	expectObjContainerArgument(g, managerContainer, webhookObj).To(HaveKeyWithValue(HealthAddrArg, ":9091"))
+	expectObjContainerArgument(g, managerContainer, webhookObj).To(HaveKeyWithValue(PrometheusPortArg, "9888"))
+	assertWebhookPorts(g, webhookObj, serviceObj, port, healthPort, metricsPort, int64(port))
+}
+
+func assertWebhookPorts(g *WithT, webhookObj, serviceObj *unstructured.Unstructured, port, healthPort, metricsPort int32, targetPort interface{}) {
+	containers, found, err := unstructured.NestedSlice(webhookObj.Object, "spec", "template", "spec", "containers")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	container := containers[0].(map[string]interface{})
+	ports, found, err := unstructured.NestedSlice(container, "ports")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	expectedPorts := map[string]int64{
+		webhookServerPortName: int64(port),
+		healthzPortName:       int64(healthPort),
+		metricsPortName:       int64(metricsPort),
+	}
+	for _, p := range ports {
+		containerPort := p.(map[string]interface{})
+		g.Expect(containerPort["containerPort"]).To(Equal(expectedPorts[containerPort["name"].(string)]))
+	}
+	for _, probe := range []string{"livenessProbe", "readinessProbe"} {
+		probePort, found, err := unstructured.NestedInt64(container, probe, "httpGet", "port")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(found).To(BeTrue())
+		g.Expect(probePort).To(Equal(int64(healthPort)))
+	}
+
+	servicePorts, found, err := unstructured.NestedSlice(serviceObj.Object, "spec", "ports")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(servicePorts[0]).To(HaveKeyWithValue("targetPort", targetPort))
+}
+
+func TestWebhookService(t *testing.T) {
+	g := NewWithT(t)
+	internalTrafficPolicy := corev1.ServiceInternalTrafficPolicyLocal
+	ipFamilyPolicy := corev1.IPFamilyPolicyPreferDualStack
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test default
+	serviceObj, err := util.GetManifestObject(WebhookServiceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, WebhookServiceFile, serviceObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serviceObj.GetNamespace()).To(Equal(namespace))
+	g.Expect(serviceObj.GetAnnotations()).To(BeEmpty())
+	g.Expect(serviceObj.GetLabels()).To(Equal(map[string]string{
+		"gatekeeper.sh/system": "yes",
+		ManagedByLabel:         managedByOperator,
+		PartOfLabel:            partOfGatekeeper,
+		VersionLabel:           manifestVersion(),
+	}))
+	_, found, err := unstructured.NestedString(serviceObj.Object, "spec", "internalTrafficPolicy")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeFalse())
+
+	// test override
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		Service: &operatorv1alpha1.ServiceConfig{
+			Annotations: map[string]string{
+				"service.beta.kubernetes.io/aws-load-balancer-internal": "true",
+			},
+			Labels: map[string]string{
+				"team": "security",
+			},
+			InternalTrafficPolicy: &internalTrafficPolicy,
+			IPFamilies:            []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol},
+			IPFamilyPolicy:        &ipFamilyPolicy,
+		},
+	}
+	err = crOverrides(gatekeeper, WebhookServiceFile, serviceObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serviceObj.GetAnnotations()).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-internal", "true"))
+	g.Expect(serviceObj.GetLabels()).To(HaveKeyWithValue("team", "security"))
+	g.Expect(serviceObj.GetLabels()).To(HaveKeyWithValue("gatekeeper.sh/system", "yes"))
+	current, found, err := unstructured.NestedString(serviceObj.Object, "spec", "internalTrafficPolicy")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(current).To(BeEquivalentTo(internalTrafficPolicy))
+	ipFamilies, found, err := unstructured.NestedStringSlice(serviceObj.Object, "spec", "ipFamilies")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(ipFamilies).To(Equal([]string{"IPv6", "IPv4"}))
+	current, found, err = unstructured.NestedString(serviceObj.Object, "spec", "ipFamilyPolicy")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(current).To(BeEquivalentTo(ipFamilyPolicy))
+}
+
+func TestMetricsService(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	_, applyOrderedAssets, _, _ := getStaticAssets(gatekeeper)
+	g.Expect(applyOrderedAssets).To(ContainElement(MetricsServiceFile))
+
+	serviceObj, err := util.GetManifestObject(MetricsServiceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, MetricsServiceFile, serviceObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serviceObj.GetNamespace()).To(Equal(namespace))
+
+	// The metrics Service selects both the audit and webhook pods
+	selector, found, err := unstructured.NestedStringMap(serviceObj.Object, "spec", "selector")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	for _, asset := range []string{AuditFile, WebhookFile} {
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		podLabels, found, err := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(found).To(BeTrue())
+		for k, v := range selector {
+			g.Expect(podLabels).To(HaveKeyWithValue(k, v))
+		}
+	}
+	ports, found, err := unstructured.NestedSlice(serviceObj.Object, "spec", "ports")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(ports[0]).To(HaveKeyWithValue("targetPort", metricsPortName))
+
+	// The ServiceMonitor only selects the metrics Service
+	serviceMonitorObj, err := util.GetManifestObject(ServiceMonitorFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	matchLabels, found, err := unstructured.NestedStringMap(serviceMonitorObj.Object, "spec", "selector", "matchLabels")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(labels.SelectorFromSet(matchLabels).Matches(labels.Set(serviceObj.GetLabels()))).To(BeTrue())
+	webhookServiceObj, err := util.GetManifestObject(WebhookServiceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, obj := range []*unstructured.Unstructured{webhookServiceObj, webhookLoadBalancerService(namespace, 443, "")} {
+		g.Expect(labels.SelectorFromSet(matchLabels).Matches(labels.Set(obj.GetLabels()))).To(BeFalse())
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	g := NewWithT(t)
+	prometheusPort := int32(9888)
+	webhookMetricsPort := int32(9889)
+	insecure := true
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test default
+	auditObj, err := util.GetManifestObject(AuditFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	webhookObj, err := util.GetManifestObject(WebhookFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, AuditFile, auditObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, obj := range []*unstructured.Unstructured{auditObj, webhookObj} {
+		expectObjContainerArgument(g, managerContainer, obj).NotTo(HaveKey(MetricsBackendArg))
+		expectObjContainerArgument(g, managerContainer, obj).NotTo(HaveKey(OTLPEndpointArg))
+	}
+
+	// test override
+	gatekeeper.Spec.Metrics = &operatorv1alpha1.MetricsConfig{
+		Backends: []operatorv1alpha1.MetricsBackend{
+			operatorv1alpha1.MetricsBackendPrometheus,
+			operatorv1alpha1.MetricsBackendOpenTelemetry,
+		},
+		PrometheusPort: &prometheusPort,
+		OTLP: &operatorv1alpha1.OTLPConfig{
+			Endpoint: "otel-collector.observability:4318",
+			Insecure: &insecure,
+		},
+	}
+	err = crOverrides(gatekeeper, AuditFile, auditObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, obj := range []*unstructured.Unstructured{auditObj, webhookObj} {
+		args := getContainerArgumentsSlice(g, managerContainer, obj)
+		g.Expect(args).To(ContainElements(
+			util.ToArg(MetricsBackendArg, string(operatorv1alpha1.MetricsBackendPrometheus)),
+			util.ToArg(MetricsBackendArg, string(operatorv1alpha1.MetricsBackendOpenTelemetry)),
+		))
+		expectObjContainerArgument(g, managerContainer, obj).To(HaveKeyWithValue(PrometheusPortArg, "9888"))
+		expectObjContainerArgument(g, managerContainer, obj).To(HaveKeyWithValue(OTLPEndpointArg, "otel-collector.observability:4318"))
+		assertContainerEnv(g, obj, OTLPInsecureEnvVar, "true")
+	}
+
+	// test OTLP without the opentelemetry backend
+	gatekeeper.Spec.Metrics.Backends = []operatorv1alpha1.MetricsBackend{operatorv1alpha1.MetricsBackendPrometheus}
+	err = crOverrides(gatekeeper, AuditFile, auditObj, namespace, false, false)
+	g.Expect(err).To(MatchError(ContainSubstring("spec.metrics.otlp requires the opentelemetry backend")))
+	gatekeeper.Spec.Metrics.Backends = nil
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).To(HaveOccurred())
+	gatekeeper.Spec.Metrics.OTLP = nil
+
+	// test webhook metricsPort precedence
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		MetricsPort: &webhookMetricsPort,
+	}
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	expectObjContainerArgument(g, managerContainer, webhookObj).To(HaveKeyWithValue(PrometheusPortArg, "9889"))
+}
+
+func assertContainerEnv(g *WithT, obj *unstructured.Unstructured, name, value string) {
+	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	env, found, err := unstructured.NestedSlice(containers[0].(map[string]interface{}), "env")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(env).To(ContainElement(map[string]interface{}{
+		"name":  name,
+		"value": value,
+	}))
+}
+
 func expectObjContainerArgument(g *WithT, containerName string, obj *unstructured.Unstructured) Assertion {
 	args := getContainerArgumentsMap(g, containerName, obj)
 	return g.Expect(args)
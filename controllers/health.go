@@ -0,0 +1,98 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const cacheSyncCheckTimeout = time.Second
+
+// CacheSyncChecker returns a health check that fails until the informer
+// caches of the manager have synced.
+func CacheSyncChecker(c cache.Cache) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), cacheSyncCheckTimeout)
+		defer cancel()
+		if !c.WaitForCacheSync(ctx) {
+			return fmt.Errorf("informer caches have not synced")
+		}
+		return nil
+	}
+}
+
+// WebhookHealthChecker returns a health check that fails while the webhook
+// of the Gatekeeper resource is unhealthy. The check passes if the Gatekeeper
+// resource does not exist.
+func WebhookHealthChecker(c client.Reader) healthz.Checker {
+	return func(req *http.Request) error {
+		gatekeeper := &operatorv1alpha1.Gatekeeper{}
+		err := c.Get(req.Context(), types.NamespacedName{Name: defaultGatekeeperCrName}, gatekeeper)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if reason := webhookUnhealthy(gatekeeper); reason != "" {
+			return fmt.Errorf("Gatekeeper webhook is unhealthy: %s", reason)
+		}
+		return nil
+	}
+}
+
+// webhookUnhealthy returns why the webhook of the given Gatekeeper resource
+// is unhealthy, or an empty string if it is healthy. The webhook is unhealthy
+// if its SLO is breached, if the policy ready gate timed out or if a probe
+// failed while it is ready. It is not unhealthy while its deployment is rolled
+// out on installs and upgrades, even though it is then Degraded since its
+// failure policy is set to Ignore.
+func webhookUnhealthy(gatekeeper *operatorv1alpha1.Gatekeeper) string {
+	if condition := webhookDegraded(gatekeeper); condition != nil {
+		switch condition.Reason {
+		case ConditionReasonSLOBreached, ConditionReasonPolicyNotReady:
+			return condition.Message
+		}
+	}
+	ready := false
+	for _, c := range gatekeeper.Status.WebhookConditions {
+		if c.Type == operatorv1alpha1.StatusReady {
+			ready = c.Status == corev1.ConditionTrue
+		}
+	}
+	if !ready {
+		return ""
+	}
+	for _, probe := range gatekeeper.Status.WebhookProbes {
+		if !probe.Succeeded {
+			return fmt.Sprintf("probe of webhook %s failed: %s", probe.Webhook, probe.Message)
+		}
+	}
+	return ""
+}
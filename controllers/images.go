@@ -0,0 +1,129 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+const imageDigestSeparator = "@sha256:"
+
+// gatekeeperComponents are the Gatekeeper components by the asset of their
+// deployment.
+var gatekeeperComponents = []struct {
+	name  string
+	asset string
+}{
+	{name: "audit", asset: AuditFile},
+	{name: "webhook", asset: WebhookFile},
+}
+
+func digestRequired(image *operatorv1alpha1.ImageConfig) bool {
+	return image != nil && image.DigestPolicy != nil && *image.DigestPolicy == operatorv1alpha1.ImageDigestRequired
+}
+
+// checkImageDigest returns an error if the image of the manager container of
+// the given deployment is not pinned to a digest while the digest policy
+// requires it.
+func checkImageDigest(obj *unstructured.Unstructured, image *operatorv1alpha1.ImageConfig) error {
+	if !digestRequired(image) {
+		return nil
+	}
+	return setContainerAttrWithFn(obj, managerContainer, func(container map[string]interface{}) error {
+		current, _, err := unstructured.NestedString(container, "image")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to retrieve container image")
+		}
+		if !strings.Contains(current, imageDigestSeparator) {
+			return errors.Errorf("Image %s of deployment %s is not pinned to a digest as required by spec.image.digestPolicy", current, obj.GetName())
+		}
+		return nil
+	})
+}
+
+// validateImageDigests checks the images of all the Gatekeeper components
+// before any resource is applied, so that an image rejected by the digest
+// policy does not leave Gatekeeper partially updated.
+func (r *GatekeeperReconciler) validateImageDigests(gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	if !digestRequired(gatekeeper.Spec.Image) {
+		return nil
+	}
+	for _, component := range gatekeeperComponents {
+		obj, err := util.GetManifestObject(component.asset)
+		if err != nil {
+			return err
+		}
+		if err := crOverrides(gatekeeper, component.asset, obj, r.gatekeeperNamespace(), r.isOpenShift(), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// podImages returns the images running in the pods of the Gatekeeper
+// components, sorted by pod name.
+func (r *GatekeeperReconciler) podImages(ctx context.Context) ([]operatorv1alpha1.PodImageStatus, error) {
+	images := []operatorv1alpha1.PodImageStatus{}
+	for _, component := range gatekeeperComponents {
+		obj, err := util.GetManifestObject(component.asset)
+		if err != nil {
+			return nil, err
+		}
+		selector, _, err := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to retrieve the selector of deployment %s", obj.GetName())
+		}
+		pods := &unstructured.UnstructuredList{}
+		pods.SetAPIVersion("v1")
+		pods.SetKind("PodList")
+		if err := r.List(ctx, pods, client.InNamespace(r.gatekeeperNamespace()), client.MatchingLabels(selector)); err != nil {
+			return nil, errors.Wrapf(err, "Unable to list %s pods in namespace %s", component.name, r.gatekeeperNamespace())
+		}
+		for _, pod := range pods.Items {
+			if pod.GetDeletionTimestamp() != nil {
+				continue
+			}
+			status := operatorv1alpha1.PodImageStatus{Pod: pod.GetName(), Component: component.name}
+			containers, _, _ := unstructured.NestedSlice(pod.Object, "spec", "containers")
+			for _, c := range containers {
+				if container, ok := c.(map[string]interface{}); ok && container["name"] == managerContainer {
+					status.Image, _ = container["image"].(string)
+				}
+			}
+			// The image ID of the container status is the digest resolved by
+			// the container runtime, even for images referenced by tag.
+			containerStatuses, _, _ := unstructured.NestedSlice(pod.Object, "status", "containerStatuses")
+			for _, c := range containerStatuses {
+				if containerStatus, ok := c.(map[string]interface{}); ok && containerStatus["name"] == managerContainer {
+					status.ImageID, _ = containerStatus["imageID"].(string)
+				}
+			}
+			images = append(images, status)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Pod < images[j].Pod })
+	return images, nil
+}
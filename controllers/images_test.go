@@ -0,0 +1,153 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+func TestComponentImage(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv(GatekeeperImageEnvVar, "openpolicyagent/gatekeeper:v3.11.1")
+	canary := "quay.io/gatekeeper/gatekeeper:v3.11.1-patched"
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Audit: &operatorv1alpha1.AuditConfig{
+				Image: &canary,
+			},
+		},
+	}
+
+	// test the audit image is overridden
+	auditObj, err := util.GetManifestObject(AuditFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, AuditFile, auditObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	image, _ := getDefaultImageConfig(g, auditObj)
+	g.Expect(image).To(Equal(canary))
+
+	// test the webhook image is not
+	webhookObj, err := util.GetManifestObject(WebhookFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, WebhookFile, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	image, _ = getDefaultImageConfig(g, webhookObj)
+	g.Expect(image).To(Equal("openpolicyagent/gatekeeper:v3.11.1"))
+}
+
+func TestImageDigestPolicy(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv(GatekeeperImageEnvVar, "openpolicyagent/gatekeeper:v3.11.1")
+	digestPolicy := operatorv1alpha1.ImageDigestRequired
+	pinned := "openpolicyagent/gatekeeper@sha256:0123"
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Image: &operatorv1alpha1.ImageConfig{
+				DigestPolicy: &digestPolicy,
+			},
+			Audit: &operatorv1alpha1.AuditConfig{
+				Image: &pinned,
+			},
+		},
+	}
+	r := &GatekeeperReconciler{Namespace: namespace}
+
+	// test tag only references are rejected
+	g.Expect(r.validateImageDigests(gatekeeper)).To(MatchError(ContainSubstring("Image openpolicyagent/gatekeeper:v3.11.1 of deployment gatekeeper-controller-manager is not pinned to a digest")))
+	auditObj, err := util.GetManifestObject(AuditFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(crOverrides(gatekeeper, AuditFile, auditObj, namespace, false, false)).To(Succeed())
+
+	// test digests are accepted
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		Image: &pinned,
+	}
+	g.Expect(r.validateImageDigests(gatekeeper)).To(Succeed())
+
+	// test optional digests
+	digestPolicy = operatorv1alpha1.ImageDigestOptional
+	gatekeeper.Spec.Webhook = nil
+	g.Expect(r.validateImageDigests(gatekeeper)).To(Succeed())
+}
+
+func TestPodImages(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+
+	auditPod := imagePod("gatekeeper-audit-1", map[string]string{
+		"control-plane":           "audit-controller",
+		"gatekeeper.sh/operation": "audit",
+		"gatekeeper.sh/system":    "yes",
+	}, "openpolicyagent/gatekeeper:v3.11.1", "docker-pullable://openpolicyagent/gatekeeper@sha256:0123")
+	webhookPod := imagePod("gatekeeper-controller-manager-1", map[string]string{
+		"control-plane":           "controller-manager",
+		"gatekeeper.sh/operation": "webhook",
+		"gatekeeper.sh/system":    "yes",
+	}, "openpolicyagent/gatekeeper:v3.11.1", "")
+	otherPod := imagePod("other", map[string]string{"app": "other"}, "other:latest", "sha256:4567")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(auditPod, webhookPod, otherPod).Build()
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace}
+
+	g.Expect(r.podImages(ctx)).To(Equal([]operatorv1alpha1.PodImageStatus{
+		{
+			Pod:       "gatekeeper-audit-1",
+			Component: "audit",
+			Image:     "openpolicyagent/gatekeeper:v3.11.1",
+			ImageID:   "docker-pullable://openpolicyagent/gatekeeper@sha256:0123",
+		},
+		{
+			Pod:       "gatekeeper-controller-manager-1",
+			Component: "webhook",
+			Image:     "openpolicyagent/gatekeeper:v3.11.1",
+		},
+	}))
+}
+
+func imagePod(name string, labels map[string]string, image, imageID string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: managerContainer, Image: image}},
+		},
+	}
+	if imageID != "" {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: managerContainer, Image: image, ImageID: imageID}}
+	}
+	return pod
+}
@@ -63,6 +63,12 @@ func retainServiceFields(desiredObj, clusterObj *unstructured.Unstructured) erro
 }
 
 func retainSecretFields(desiredObj, clusterObj *unstructured.Unstructured) error {
+	// Data set on the desired object e.g. operator managed certificates takes
+	// precedence over the data in the cluster.
+	if desiredData, ok, _ := unstructured.NestedMap(desiredObj.Object, "data"); ok && len(desiredData) != 0 {
+		return nil
+	}
+
 	data, ok, err := unstructured.NestedMap(clusterObj.Object, "data")
 	if err != nil {
 		return errors.Wrap(err, "Error retrieving data from secret")
@@ -106,6 +112,13 @@ func retainWebhookConfigurationFields(desiredObj, clusterObj *unstructured.Unstr
 				continue
 			}
 
+			// The caBundle of webhooks configured with a URL is managed by
+			// the operator rather than injected by Gatekeeper, so it is not
+			// retained.
+			if _, ok, _ := unstructured.NestedFieldNoCopy(desiredWebhook, "clientConfig", "url"); ok {
+				break
+			}
+
 			caBundle, ok, err := unstructured.NestedFieldNoCopy(clusterWebhook, "clientConfig", "caBundle")
 			if err != nil {
 				return errors.Wrapf(err, "Error retrieving webhooks[%d].clientConfig.caBundle from cluster object %s", j, clusterObj.GetKind())
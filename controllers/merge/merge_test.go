@@ -88,3 +88,85 @@ func TestRetainClusterObjectFields(t *testing.T) {
 		}
 	}
 }
+
+func TestRetainClusterObjectFieldsURLClientConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	desiredCABundle := "ZGVzaXJlZCBDQUJ1bmRsZQo="
+	desiredObj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind": util.ValidatingWebhookConfigurationKind,
+			"webhooks": []interface{}{
+				map[string]interface{}{
+					"clientConfig": map[string]interface{}{
+						"caBundle": desiredCABundle,
+						"url":      "https://gatekeeper.example.com:443/v1/admit",
+					},
+				},
+			},
+		},
+	}
+	clusterObj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind": util.ValidatingWebhookConfigurationKind,
+			"webhooks": []interface{}{
+				map[string]interface{}{
+					"clientConfig": map[string]interface{}{
+						"caBundle": "Y2x1c3RlciBDQUJ1bmRsZSBpcyBzZXQK",
+					},
+				},
+			},
+		},
+	}
+
+	err := RetainClusterObjectFields(desiredObj, clusterObj)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	desiredWebhooks, found, err := unstructured.NestedSlice(desiredObj.Object, "webhooks")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	caBundle, found, err := unstructured.NestedString(desiredWebhooks[0].(map[string]interface{}), "clientConfig", "caBundle")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(caBundle).To(Equal(desiredCABundle))
+}
+
+func TestRetainSecretFields(t *testing.T) {
+	g := NewWithT(t)
+
+	clusterData := map[string]interface{}{"tls.crt": "Y2x1c3Rlcgo="}
+	clusterObj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind": util.SecretKind,
+			"data": clusterData,
+		},
+	}
+
+	// Cluster data is retained when no data is desired
+	desiredObj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind": util.SecretKind,
+		},
+	}
+	err := RetainClusterObjectFields(desiredObj, clusterObj)
+	g.Expect(err).ToNot(HaveOccurred())
+	data, found, err := unstructured.NestedMap(desiredObj.Object, "data")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(data).To(Equal(clusterData))
+
+	// Desired data takes precedence
+	desiredData := map[string]interface{}{"tls.crt": "ZGVzaXJlZAo="}
+	desiredObj = &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind": util.SecretKind,
+			"data": desiredData,
+		},
+	}
+	err = RetainClusterObjectFields(desiredObj, clusterObj)
+	g.Expect(err).ToNot(HaveOccurred())
+	data, found, err = unstructured.NestedMap(desiredObj.Object, "data")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(data).To(Equal(desiredData))
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/version"
+)
+
+const metricsNamespace = "gatekeeper_operator"
+
+// Values of the result label of the reconcile duration metric.
+const (
+	reconcileResultSuccess = "success"
+	reconcileResultRequeue = "requeue"
+	reconcileResultError   = "error"
+)
+
+// Values of the result label of the webhook probe duration metric.
+const (
+	probeResultSuccess = "success"
+	probeResultFailure = "failure"
+)
+
+// Values of the operation label of the asset operations metric.
+const (
+	assetOperationCreate = "create"
+	assetOperationUpdate = "update"
+	assetOperationDelete = "delete"
+	assetOperationSkip   = "skip"
+	assetOperationError  = "error"
+)
+
+var (
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of the Gatekeeper reconciliations in seconds.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"result"},
+	)
+
+	assetOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "asset_operations_total",
+			Help:      "Number of create, update, delete, skip and error operations per Gatekeeper asset.",
+		},
+		[]string{"asset", "operation"},
+	)
+
+	webhookReadinessWait = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_readiness_wait_seconds",
+			Help:      "Time waited for the Gatekeeper webhook deployment to become ready in seconds.",
+			Buckets:   []float64{5, 10, 30, 60, 120, 300, 600, 1800},
+		},
+	)
+
+	webhookFailurePolicy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_failure_policy",
+			Help:      "Effective failure policy of each Gatekeeper webhook, 1 for the policy in use and 0 otherwise.",
+		},
+		[]string{"webhook", "policy"},
+	)
+
+	webhookProbeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_probe_duration_seconds",
+			Help:      "Duration of the synthetic probes of each Gatekeeper webhook in seconds.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		[]string{"webhook", "result"},
+	)
+
+	webhookProbeSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_probe_success",
+			Help:      "Whether the last synthetic probe of each Gatekeeper webhook succeeded, 1 if it succeeded and 0 otherwise.",
+		},
+		[]string{"webhook"},
+	)
+
+	webhookCircuitBreakerOpen = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "webhook_circuit_breaker_open",
+			Help:      "Whether the Gatekeeper webhook circuit breaker is open, 1 if open and 0 otherwise.",
+		},
+	)
+
+	buildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "build_info",
+			Help:      "Build information of the Gatekeeper operator, always 1.",
+		},
+		[]string{"git_version", "git_commit", "git_tree_state", "build_date", "go_version", "platform"},
+	)
+)
+
+// registerMetrics registers the operator metrics, including the collector
+// of the webhook certificate expiry of the given reconciler, with the
+// controller-runtime metrics registry. Metrics that are already registered,
+// e.g. by a previous call, are kept.
+func registerMetrics(r *GatekeeperReconciler) error {
+	collectors := []prometheus.Collector{
+		reconcileDuration,
+		assetOperations,
+		webhookReadinessWait,
+		webhookFailurePolicy,
+		webhookCircuitBreakerOpen,
+		webhookProbeDuration,
+		webhookProbeSuccess,
+		buildInfo,
+		&webhookCertificateCollector{r: r},
+	}
+	for _, collector := range collectors {
+		if err := metrics.Registry.Register(collector); err != nil {
+			are := prometheus.AlreadyRegisteredError{}
+			if errors.As(err, &are) {
+				continue
+			}
+			return errors.Wrap(err, "Unable to register metrics")
+		}
+	}
+
+	info := version.Get()
+	buildInfo.WithLabelValues(info.GitVersion, info.GitCommit, info.GitTreeState, info.BuildDate, info.GoVersion, info.Platform).Set(1)
+	return nil
+}
+
+func recordAssetOperation(asset, operation string) {
+	assetOperations.WithLabelValues(asset, operation).Inc()
+}
+
+// recordWebhookFailurePolicies records the failure policy of each webhook of
+// the given webhook configuration. The series are removed when the webhook
+// configuration is deleted.
+func recordWebhookFailurePolicies(obj *unstructured.Unstructured, deleted bool) {
+	webhooks, _, _ := unstructured.NestedSlice(obj.Object, "webhooks")
+	for _, w := range webhooks {
+		webhook, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(webhook, "name")
+		policy, _, _ := unstructured.NestedString(webhook, "failurePolicy")
+		for _, p := range []admregv1.FailurePolicyType{admregv1.Ignore, admregv1.Fail} {
+			if deleted {
+				webhookFailurePolicy.DeleteLabelValues(name, string(p))
+				continue
+			}
+			value := 0.0
+			if policy == string(p) {
+				value = 1
+			}
+			webhookFailurePolicy.WithLabelValues(name, string(p)).Set(value)
+		}
+	}
+}
+
+// recordWebhookProbe records the result of the given webhook probe.
+func recordWebhookProbe(probe operatorv1alpha1.WebhookProbeStatus) {
+	result, success := probeResultFailure, 0.0
+	if probe.Succeeded {
+		result, success = probeResultSuccess, 1
+	}
+	webhookProbeDuration.WithLabelValues(probe.Webhook, result).Observe(probe.Latency.Seconds())
+	webhookProbeSuccess.WithLabelValues(probe.Webhook).Set(success)
+}
+
+// recordWebhookReadiness tracks the time the webhook deployment has been
+// pending and records the total wait once it becomes ready.
+func (r *GatekeeperReconciler) recordWebhookReadiness(pending bool, now time.Time) {
+	switch {
+	case pending && r.webhookPendingSince.IsZero():
+		r.webhookPendingSince = now
+	case !pending && !r.webhookPendingSince.IsZero():
+		webhookReadinessWait.Observe(now.Sub(r.webhookPendingSince).Seconds())
+		r.webhookPendingSince = time.Time{}
+	}
+}
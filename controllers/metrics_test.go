@@ -0,0 +1,126 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	g := NewWithT(t)
+	r := &GatekeeperReconciler{Log: ctrl.Log}
+	g.Expect(registerMetrics(r)).To(Succeed())
+	// test the registration is idempotent, e.g. for a second manager
+	g.Expect(registerMetrics(r)).To(Succeed())
+	g.Expect(testutil.CollectAndCount(buildInfo)).To(Equal(1))
+}
+
+func TestRecordWebhookFailurePolicies(t *testing.T) {
+	g := NewWithT(t)
+	fail := admregv1.Fail
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Webhook: &operatorv1alpha1.WebhookConfig{
+				FailurePolicy: &fail,
+			},
+		},
+	}
+	webhookObj, err := util.GetManifestObject(ValidatingWebhookConfiguration)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// test pending deployment
+	err = crOverrides(gatekeeper, ValidatingWebhookConfiguration, webhookObj, namespace, false, true)
+	g.Expect(err).ToNot(HaveOccurred())
+	recordWebhookFailurePolicies(webhookObj, false)
+	g.Expect(testutil.ToFloat64(webhookFailurePolicy.WithLabelValues(ValidationGatekeeperWebhook, string(admregv1.Ignore)))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(webhookFailurePolicy.WithLabelValues(ValidationGatekeeperWebhook, string(admregv1.Fail)))).To(Equal(0.0))
+
+	// test ready deployment
+	err = crOverrides(gatekeeper, ValidatingWebhookConfiguration, webhookObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	recordWebhookFailurePolicies(webhookObj, false)
+	g.Expect(testutil.ToFloat64(webhookFailurePolicy.WithLabelValues(ValidationGatekeeperWebhook, string(admregv1.Ignore)))).To(Equal(0.0))
+	g.Expect(testutil.ToFloat64(webhookFailurePolicy.WithLabelValues(ValidationGatekeeperWebhook, string(admregv1.Fail)))).To(Equal(1.0))
+
+	// test deleted
+	recordWebhookFailurePolicies(webhookObj, true)
+	g.Expect(testutil.CollectAndCount(webhookFailurePolicy)).To(Equal(0))
+}
+
+func TestRecordWebhookReadiness(t *testing.T) {
+	g := NewWithT(t)
+	r := &GatekeeperReconciler{}
+	now := time.Now()
+	count := webhookReadinessWaitSampleCount(g)
+
+	r.recordWebhookReadiness(true, now)
+	r.recordWebhookReadiness(true, now.Add(time.Minute))
+	g.Expect(r.webhookPendingSince).To(Equal(now))
+
+	r.recordWebhookReadiness(false, now.Add(2*time.Minute))
+	g.Expect(r.webhookPendingSince.IsZero()).To(BeTrue())
+	g.Expect(webhookReadinessWaitSampleCount(g)).To(Equal(count + 1))
+}
+
+func webhookReadinessWaitSampleCount(g *WithT) uint64 {
+	m := &dto.Metric{}
+	g.Expect(webhookReadinessWait.Write(m)).To(Succeed())
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestReconcileDurationResult(t *testing.T) {
+	g := NewWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return errors.New("unavailable")
+		},
+	}).Build()
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace}
+	errorCount := reconcileDurationSampleCount(g, reconcileResultError)
+	successCount := reconcileDurationSampleCount(g, reconcileResultSuccess)
+
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: defaultGatekeeperCrName}})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(reconcileDurationSampleCount(g, reconcileResultError)).To(Equal(errorCount + 1))
+	g.Expect(reconcileDurationSampleCount(g, reconcileResultSuccess)).To(Equal(successCount))
+}
+
+func reconcileDurationSampleCount(g *WithT, result string) uint64 {
+	m := &dto.Metric{}
+	g.Expect(reconcileDuration.WithLabelValues(result).(prometheus.Histogram).Write(m)).To(Succeed())
+	return m.GetHistogram().GetSampleCount()
+}
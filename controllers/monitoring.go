@@ -0,0 +1,274 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	ServiceMonitorFile        = "monitoring.coreos.com_v1_servicemonitor_gatekeeper-metrics.yaml"
+	PrometheusRuleFile        = "monitoring.coreos.com_v1_prometheusrule_gatekeeper-alerts.yaml"
+	DashboardFile             = "v1_configmap_gatekeeper-dashboard.yaml"
+	PrometheusRoleFile        = "rbac.authorization.k8s.io_v1_role_gatekeeper-prometheus-k8s.yaml"
+	PrometheusRoleBindingFile = "rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-prometheus-k8s.yaml"
+	ClusterMonitoringLabel    = "openshift.io/cluster-monitoring"
+	// OperatorServiceMonitorName is the name of the ServiceMonitor of the
+	// operator metrics in the operator namespace.
+	OperatorServiceMonitorName  = "gatekeeper-operator-metrics"
+	operatorServiceMonitorAsset = "operator-service-monitor"
+	// operatorMetricsServiceLabel is the label selecting the operator metrics
+	// Service, which is added to all the operator resources by kustomize.
+	operatorMetricsServiceLabel = "control-plane"
+	operatorMetricsServiceValue = "gatekeeper-operator-controller-manager"
+)
+
+func monitoringModeEnabled(mode *operatorv1alpha1.MonitoringMode) bool {
+	return mode != nil && *mode == operatorv1alpha1.MonitoringEnabled
+}
+
+func serviceMonitorEnabled(monitoring *operatorv1alpha1.MonitoringConfig) bool {
+	return monitoring != nil && monitoringModeEnabled(monitoring.ServiceMonitor)
+}
+
+func prometheusRuleEnabled(monitoring *operatorv1alpha1.MonitoringConfig) bool {
+	return monitoring != nil && monitoringModeEnabled(monitoring.PrometheusRule)
+}
+
+func dashboardsEnabled(monitoring *operatorv1alpha1.MonitoringConfig) bool {
+	return monitoring != nil && monitoringModeEnabled(monitoring.Dashboards)
+}
+
+// getMonitoringAssets returns the monitoring assets to apply and delete. The
+// Role and RoleBinding granting the OpenShift cluster monitoring Prometheus
+// access to the Gatekeeper namespace are only managed on OpenShift. The
+// prometheus-operator resources are only managed if its API is available,
+// whereas the dashboards are plain ConfigMaps and always managed.
+func getMonitoringAssets(gatekeeper *operatorv1alpha1.Gatekeeper, isOpenShift, hasPrometheusOperator bool) (applyMonitoringAssets, deleteMonitoringAssets []string) {
+	applyMonitoringAssets = []string{}
+	deleteMonitoringAssets = []string{}
+	add := func(enabled bool, assets ...string) {
+		if enabled {
+			applyMonitoringAssets = append(applyMonitoringAssets, assets...)
+		} else {
+			deleteMonitoringAssets = append(deleteMonitoringAssets, assets...)
+		}
+	}
+
+	monitoring := gatekeeper.Spec.Monitoring
+	if hasPrometheusOperator {
+		serviceMonitorAssets := []string{ServiceMonitorFile}
+		if isOpenShift {
+			serviceMonitorAssets = append(serviceMonitorAssets, PrometheusRoleFile, PrometheusRoleBindingFile)
+		}
+		add(serviceMonitorEnabled(monitoring), serviceMonitorAssets...)
+		add(prometheusRuleEnabled(monitoring), PrometheusRuleFile)
+	}
+	add(dashboardsEnabled(monitoring), DashboardFile)
+	return applyMonitoringAssets, deleteMonitoringAssets
+}
+
+func (r *GatekeeperReconciler) applyMonitoringAssets(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	hasPrometheusOperator := r.PlatformInfo.HasPrometheusOperator()
+	monitoring := gatekeeper.Spec.Monitoring
+	if !hasPrometheusOperator && (serviceMonitorEnabled(monitoring) || prometheusRuleEnabled(monitoring)) {
+		r.Log.Info("WARNING: monitoring.coreos.com API group not found, skipping the creation of prometheus-operator resources")
+	}
+
+	applyMonitoringAssets, deleteMonitoringAssets := getMonitoringAssets(gatekeeper, r.isOpenShift(), hasPrometheusOperator)
+	if err := r.deleteAssets(ctx, deleteMonitoringAssets, gatekeeper); err != nil {
+		return err
+	}
+	if err := r.applyAssets(ctx, applyMonitoringAssets, gatekeeper, false); err != nil {
+		return err
+	}
+
+	// The operator metrics are scraped along with the Gatekeeper metrics,
+	// since the PrometheusRule alerts on the webhook certificate expiry
+	// exported by the operator.
+	if !hasPrometheusOperator || r.OperatorNamespace == "" {
+		return nil
+	}
+	serviceMonitor := operatorServiceMonitor(r.OperatorNamespace)
+	operation := delete
+	if serviceMonitorEnabled(monitoring) {
+		if err := serviceMonitorOverrides(serviceMonitor, monitoring); err != nil {
+			return err
+		}
+		setCommonMetadata(serviceMonitor, gatekeeper.Spec)
+		setInventoryLabels(serviceMonitor, gatekeeper)
+		operation = apply
+	}
+	return r.crudResource(ctx, operatorServiceMonitorAsset, serviceMonitor, gatekeeper, operation)
+}
+
+// operatorServiceMonitor returns the ServiceMonitor of the operator metrics
+// in the given namespace. The operator metrics Service is served by the
+// kube-rbac-proxy sidecar, which authenticates Prometheus with its service
+// account token.
+func operatorServiceMonitor(namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "monitoring.coreos.com/v1",
+			"kind":       "ServiceMonitor",
+			"metadata": map[string]interface{}{
+				"name":      OperatorServiceMonitorName,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"endpoints": []interface{}{
+					map[string]interface{}{
+						"bearerTokenFile": "/var/run/secrets/kubernetes.io/serviceaccount/token",
+						"interval":        "30s",
+						"path":            "/metrics",
+						"port":            "https",
+						"scheme":          "https",
+						"tlsConfig": map[string]interface{}{
+							"insecureSkipVerify": true,
+						},
+					},
+				},
+				"selector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						operatorMetricsServiceLabel: operatorMetricsServiceValue,
+					},
+				},
+			},
+		},
+	}
+}
+
+func serviceMonitorOverrides(obj *unstructured.Unstructured, monitoring *operatorv1alpha1.MonitoringConfig) error {
+	if monitoring == nil {
+		return nil
+	}
+	setMonitoringLabels(obj, monitoring.Labels)
+	if monitoring.Interval != nil {
+		interval := fmt.Sprintf("%ds", int64(monitoring.Interval.Round(time.Second).Seconds()))
+		endpoints, found, err := unstructured.NestedSlice(obj.Object, "spec", "endpoints")
+		if err != nil || !found {
+			return errors.Wrapf(err, "Failed to retrieve ServiceMonitor endpoints")
+		}
+		for _, e := range endpoints {
+			endpoint := e.(map[string]interface{})
+			if err := unstructured.SetNestedField(endpoint, interval, "interval"); err != nil {
+				return errors.Wrapf(err, "Failed to set ServiceMonitor endpoint interval")
+			}
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, endpoints, "spec", "endpoints"); err != nil {
+			return errors.Wrapf(err, "Failed to set ServiceMonitor endpoints")
+		}
+	}
+	return nil
+}
+
+func setMonitoringLabels(obj *unstructured.Unstructured, monitoringLabels map[string]string) {
+	if monitoringLabels != nil {
+		obj.SetLabels(mergeStringMaps(obj.GetLabels(), monitoringLabels))
+	}
+}
+
+// setClusterMonitoringLabel labels the Gatekeeper namespace so that it is
+// scraped and its alerts evaluated by the OpenShift cluster monitoring stack.
+// The cluster monitoring Prometheus selects ServiceMonitors and
+// PrometheusRules by their namespace label only, so the objects themselves
+// are not labeled.
+func setClusterMonitoringLabel(obj *unstructured.Unstructured, monitoring *operatorv1alpha1.MonitoringConfig) error {
+	if !serviceMonitorEnabled(monitoring) && !prometheusRuleEnabled(monitoring) {
+		return nil
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ClusterMonitoringLabel] = "true"
+	obj.SetLabels(labels)
+	return nil
+}
+
+var webhookCertificateExpiryDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metricsNamespace, "webhook", "certificate_expiry_timestamp_seconds"),
+	"Expiry time of the Gatekeeper webhook serving certificate in seconds since the epoch.",
+	nil, nil,
+)
+
+// webhookCertificateCollector exports the expiry of the webhook serving
+// certificate. The certificate Secret is read on each scrape since it is
+// rotated by Gatekeeper independently of the reconciliation of the operator.
+type webhookCertificateCollector struct {
+	r *GatekeeperReconciler
+}
+
+func (c *webhookCertificateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- webhookCertificateExpiryDesc
+}
+
+func (c *webhookCertificateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// The collector runs concurrently with the reconciliation, so the
+	// namespace Gatekeeper is deployed to is read from the Gatekeeper resource.
+	gatekeeper := &operatorv1alpha1.Gatekeeper{}
+	if err := c.r.Get(ctx, types.NamespacedName{Name: defaultGatekeeperCrName}, gatekeeper); err != nil {
+		if !apierrors.IsNotFound(err) {
+			c.r.Log.Error(err, "Unable to collect the webhook certificate expiry")
+		}
+		return
+	}
+	data, err := c.r.getWebhookServerCertData(ctx, c.r.deployedNamespace(gatekeeper), WebhookServerCertSecretName)
+	if err != nil {
+		c.r.Log.Error(err, "Unable to collect the webhook certificate expiry")
+		return
+	}
+	// The Secret is populated asynchronously, so no metric is exported until
+	// a certificate is available.
+	if len(data[CertName]) == 0 {
+		return
+	}
+	notAfter, err := certificateNotAfter(data[CertName])
+	if err != nil {
+		c.r.Log.Error(err, "Unable to collect the webhook certificate expiry")
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(webhookCertificateExpiryDesc, prometheus.GaugeValue, float64(notAfter.Unix()))
+}
+
+// certificateNotAfter returns the expiry of the first certificate in the
+// given PEM data.
+func certificateNotAfter(certPEM []byte) (time.Time, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("failed to decode PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
@@ -0,0 +1,269 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/platform"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+func TestGetMonitoringAssets(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test default
+	applyAssets, deleteAssets := getMonitoringAssets(gatekeeper, false, true)
+	g.Expect(applyAssets).To(BeEmpty())
+	g.Expect(deleteAssets).To(ConsistOf(ServiceMonitorFile, PrometheusRuleFile, DashboardFile))
+
+	// test enabled
+	enabled := operatorv1alpha1.MonitoringEnabled
+	gatekeeper.Spec.Monitoring = &operatorv1alpha1.MonitoringConfig{
+		ServiceMonitor: &enabled,
+	}
+	applyAssets, deleteAssets = getMonitoringAssets(gatekeeper, false, true)
+	g.Expect(applyAssets).To(ConsistOf(ServiceMonitorFile))
+	g.Expect(deleteAssets).To(ConsistOf(PrometheusRuleFile, DashboardFile))
+
+	// test enabled on OpenShift
+	applyAssets, deleteAssets = getMonitoringAssets(gatekeeper, true, true)
+	g.Expect(applyAssets).To(ConsistOf(ServiceMonitorFile, PrometheusRoleFile, PrometheusRoleBindingFile))
+	g.Expect(deleteAssets).To(ConsistOf(PrometheusRuleFile, DashboardFile))
+
+	// test disabled on OpenShift
+	disabled := operatorv1alpha1.MonitoringDisabled
+	gatekeeper.Spec.Monitoring.ServiceMonitor = &disabled
+	applyAssets, deleteAssets = getMonitoringAssets(gatekeeper, true, true)
+	g.Expect(applyAssets).To(BeEmpty())
+	g.Expect(deleteAssets).To(ConsistOf(ServiceMonitorFile, PrometheusRoleFile, PrometheusRoleBindingFile, PrometheusRuleFile, DashboardFile))
+
+	// test alerts and dashboards enabled
+	gatekeeper.Spec.Monitoring.PrometheusRule = &enabled
+	gatekeeper.Spec.Monitoring.Dashboards = &enabled
+	applyAssets, deleteAssets = getMonitoringAssets(gatekeeper, false, true)
+	g.Expect(applyAssets).To(ConsistOf(PrometheusRuleFile, DashboardFile))
+	g.Expect(deleteAssets).To(ConsistOf(ServiceMonitorFile))
+
+	// test without the prometheus-operator
+	applyAssets, deleteAssets = getMonitoringAssets(gatekeeper, false, false)
+	g.Expect(applyAssets).To(ConsistOf(DashboardFile))
+	g.Expect(deleteAssets).To(BeEmpty())
+}
+
+func TestServiceMonitor(t *testing.T) {
+	g := NewWithT(t)
+	enabled := operatorv1alpha1.MonitoringEnabled
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	// test default
+	serviceMonitorObj, err := util.GetManifestObject(ServiceMonitorFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, ServiceMonitorFile, serviceMonitorObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serviceMonitorObj.GetNamespace()).To(Equal(namespace))
+	assertServiceMonitorEndpoint(g, serviceMonitorObj, "30s")
+
+	// test override
+	gatekeeper.Spec.Monitoring = &operatorv1alpha1.MonitoringConfig{
+		ServiceMonitor: &enabled,
+		Interval:       &metav1.Duration{Duration: time.Minute},
+		Labels: map[string]string{
+			"prometheus": "platform",
+		},
+	}
+	err = crOverrides(gatekeeper, ServiceMonitorFile, serviceMonitorObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(serviceMonitorObj.GetLabels()).To(HaveKeyWithValue("prometheus", "platform"))
+	g.Expect(serviceMonitorObj.GetLabels()).To(HaveKeyWithValue("gatekeeper.sh/system", "yes"))
+	assertServiceMonitorEndpoint(g, serviceMonitorObj, "60s")
+}
+
+func assertServiceMonitorEndpoint(g *WithT, obj *unstructured.Unstructured, expectedInterval string) {
+	endpoints, found, err := unstructured.NestedSlice(obj.Object, "spec", "endpoints")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(endpoints).To(HaveLen(1))
+	endpoint := endpoints[0].(map[string]interface{})
+	g.Expect(endpoint).To(HaveKeyWithValue("port", metricsPortName))
+	g.Expect(endpoint).To(HaveKeyWithValue("interval", expectedInterval))
+}
+
+func TestOperatorServiceMonitor(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	operatorNamespace := "gatekeeper-operator"
+	r := &GatekeeperReconciler{
+		Client:            c,
+		Log:               ctrl.Log,
+		Scheme:            scheme,
+		Namespace:         namespace,
+		OperatorNamespace: operatorNamespace,
+		PlatformInfo:      platform.PlatformInfo{Name: platform.Kubernetes, PrometheusOperator: true},
+		namespace:         namespace,
+	}
+	enabled := operatorv1alpha1.MonitoringEnabled
+	interval := metav1.Duration{Duration: time.Minute}
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+		},
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Monitoring: &operatorv1alpha1.MonitoringConfig{
+				ServiceMonitor: &enabled,
+				Interval:       &interval,
+			},
+		},
+	}
+	getServiceMonitor := func() (*unstructured.Unstructured, error) {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("monitoring.coreos.com/v1")
+		obj.SetKind("ServiceMonitor")
+		err := c.Get(ctx, types.NamespacedName{Namespace: operatorNamespace, Name: OperatorServiceMonitorName}, obj)
+		return obj, err
+	}
+
+	// test the operator metrics are scraped along with the Gatekeeper metrics
+	g.Expect(r.applyMonitoringAssets(ctx, gatekeeper)).To(Succeed())
+	serviceMonitorObj, err := getServiceMonitor()
+	g.Expect(err).ToNot(HaveOccurred())
+	endpoints, found, err := unstructured.NestedSlice(serviceMonitorObj.Object, "spec", "endpoints")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(endpoints).To(HaveLen(1))
+	g.Expect(endpoints[0]).To(HaveKeyWithValue("port", "https"))
+	g.Expect(endpoints[0]).To(HaveKeyWithValue("interval", "60s"))
+	matchLabels, _, err := unstructured.NestedStringMap(serviceMonitorObj.Object, "spec", "selector", "matchLabels")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(matchLabels).To(HaveKeyWithValue("control-plane", "gatekeeper-operator-controller-manager"))
+
+	// test the ServiceMonitor is deleted once disabled
+	gatekeeper.Spec.Monitoring = nil
+	g.Expect(r.applyMonitoringAssets(ctx, gatekeeper)).To(Succeed())
+	_, err = getServiceMonitor()
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestClusterMonitoringLabel(t *testing.T) {
+	g := NewWithT(t)
+	enabled := operatorv1alpha1.MonitoringEnabled
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Monitoring: &operatorv1alpha1.MonitoringConfig{
+				ServiceMonitor: &enabled,
+			},
+		},
+	}
+	// test Kubernetes
+	namespaceObj, err := util.GetManifestObject(NamespaceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, NamespaceFile, namespaceObj, namespace, false, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(namespaceObj.GetLabels()).NotTo(HaveKey(ClusterMonitoringLabel))
+
+	// test OpenShift
+	namespaceObj, err = util.GetManifestObject(NamespaceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, NamespaceFile, namespaceObj, namespace, true, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(namespaceObj.GetLabels()).To(HaveKeyWithValue(ClusterMonitoringLabel, "true"))
+
+	// test OpenShift with monitoring disabled
+	gatekeeper.Spec.Monitoring = nil
+	namespaceObj, err = util.GetManifestObject(NamespaceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	err = crOverrides(gatekeeper, NamespaceFile, namespaceObj, namespace, true, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(namespaceObj.GetLabels()).NotTo(HaveKey(ClusterMonitoringLabel))
+}
+
+func TestPrometheusRuleAndDashboard(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	for _, asset := range []string{PrometheusRuleFile, DashboardFile} {
+		// test default
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		err = crOverrides(gatekeeper, asset, obj, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(obj.GetNamespace()).To(Equal(namespace))
+
+		// test labels override
+		gatekeeper.Spec.Monitoring = &operatorv1alpha1.MonitoringConfig{
+			Labels: map[string]string{
+				"prometheus": "platform",
+			},
+		}
+		err = crOverrides(gatekeeper, asset, obj, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue("prometheus", "platform"))
+		g.Expect(obj.GetLabels()).To(HaveKeyWithValue("gatekeeper.sh/system", "yes"))
+		gatekeeper.Spec.Monitoring = nil
+	}
+
+	dashboardObj, err := util.GetManifestObject(DashboardFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(dashboardObj.GetLabels()).To(HaveKeyWithValue("grafana_dashboard", "1"))
+	dashboard, found, err := unstructured.NestedString(dashboardObj.Object, "data", "gatekeeper.json")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).To(BeTrue())
+	g.Expect(json.Valid([]byte(dashboard))).To(BeTrue())
+}
+
+func TestCertificateNotAfter(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Now()
+	data, err := generateWebhookCertificates(webhookCertHosts(namespace), now)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	notAfter, err := certificateNotAfter(data[CertName])
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(notAfter.Unix()).To(Equal(now.Add(certValidityDuration).Unix()))
+
+	_, err = certificateNotAfter([]byte("invalid"))
+	g.Expect(err).To(HaveOccurred())
+}
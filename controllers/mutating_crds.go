@@ -0,0 +1,134 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+const (
+	// MutatorsArchiveName is the name of the archive the mutators are backed
+	// up into before the mutating CRDs are deleted.
+	MutatorsArchiveName            = "mutators"
+	blockedMutatorsRequeueInterval = time.Minute
+)
+
+// mutatorCRDs are the mutating CRDs whose custom resources are user data,
+// unlike the MutatorPodStatuses written by the webhook pods.
+var mutatorCRDs = []string{
+	AssignCRDFile,
+	AssignMetadataCRDFile,
+}
+
+func mutatingCRDDeletion(gatekeeper *operatorv1alpha1.Gatekeeper) operatorv1alpha1.MutatingCRDDeletionPolicy {
+	if gatekeeper.Spec.MutatingCRDDeletion != nil {
+		return *gatekeeper.Spec.MutatingCRDDeletion
+	}
+	return operatorv1alpha1.MutatingCRDDeletionRefuse
+}
+
+// crdListGVK returns the list kind of the storage version of the CRD of the
+// given asset.
+func crdListGVK(asset string) (schema.GroupVersionKind, error) {
+	crd, err := util.GetManifestObject(asset)
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+	listKind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "listKind")
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok || version["storage"] != true {
+			continue
+		}
+		name, _ := version["name"].(string)
+		return schema.GroupVersionKind{Group: group, Version: name, Kind: listKind}, nil
+	}
+	return schema.GroupVersionKind{}, fmt.Errorf("no storage version found in CRD %s", crd.GetName())
+}
+
+// listMutators returns the custom resources of the mutator CRDs except the
+// probe mutator. CRDs that do not exist have no custom resources.
+func (r *GatekeeperReconciler) listMutators(ctx context.Context) ([]unstructured.Unstructured, error) {
+	var mutators []unstructured.Unstructured
+	for _, asset := range mutatorCRDs {
+		gvk, err := crdListGVK(asset)
+		if err != nil {
+			return nil, err
+		}
+		items, err := r.listUnstructured(ctx, gvk, "")
+		if err != nil {
+			if meta.IsNoMatchError(errors.Cause(err)) || apierrors.IsNotFound(errors.Cause(err)) {
+				continue
+			}
+			return nil, err
+		}
+		mutators = append(mutators, withoutProbeObjects(items)...)
+	}
+	return mutators, nil
+}
+
+// guardMutatingCRDDeletion returns the number of mutators that block the
+// deletion of the given mutating CRDs, or zero if they may be deleted. Unless
+// the mutating CRD deletion policy is Force, the deletion is refused while
+// mutators exist. With the Backup policy, the mutators are exported before the
+// deletion proceeds.
+func (r *GatekeeperReconciler) guardMutatingCRDDeletion(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, deleteCRDAssets []string) (int32, error) {
+	if len(deleteCRDAssets) == 0 {
+		return 0, nil
+	}
+	policy := mutatingCRDDeletion(gatekeeper)
+	if policy == operatorv1alpha1.MutatingCRDDeletionForce {
+		return 0, nil
+	}
+
+	mutators, err := r.listMutators(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(mutators) == 0 {
+		return 0, nil
+	}
+	if policy == operatorv1alpha1.MutatingCRDDeletionRefuse {
+		if gatekeeper.Status.BlockedMutators == 0 {
+			r.Log.Info("Refusing to delete the mutating CRDs while mutators exist", "mutators", len(mutators))
+			r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonCRDDeletionBlocked,
+				"Refusing to delete the mutating CRDs and their %d mutators, set spec.mutatingCRDDeletion to Backup or Force to proceed", len(mutators))
+		}
+		return int32(len(mutators)), nil
+	}
+
+	if err := r.writePolicyArchive(ctx, gatekeeper, MutatorsArchiveName, mutators); err != nil {
+		return 0, err
+	}
+	r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonBackedUp,
+		"Backed up %d mutators into archive %s before deleting the mutating CRDs", len(mutators), MutatorsArchiveName)
+	return 0, nil
+}
@@ -0,0 +1,95 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+func TestCRDListGVK(t *testing.T) {
+	g := NewWithT(t)
+	gvk, err := crdListGVK(AssignCRDFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(gvk.Group).To(Equal("mutations.gatekeeper.sh"))
+	g.Expect(gvk.Version).To(Equal("v1"))
+	g.Expect(gvk.Kind).To(Equal("AssignList"))
+}
+
+func TestGuardMutatingCRDDeletion(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	assignListGVK, err := crdListGVK(AssignCRDFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, asset := range mutatorCRDs {
+		gvk, err := crdListGVK(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		scheme.AddKnownTypeWithName(gvk, &unstructured.UnstructuredList{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind[:len(gvk.Kind)-len("List")]), &unstructured.Unstructured{})
+	}
+
+	assign := policyObject(assignListGVK, "", "set-owner", "assign-uid", map[string]interface{}{"byPod": []interface{}{}})
+	// The probe mutator neither blocks the deletion nor is backed up.
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(assign, probeMutator()).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace, Recorder: recorder}
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+		},
+	}
+
+	// test no CRDs to delete
+	g.Expect(r.guardMutatingCRDDeletion(ctx, gatekeeper, nil)).To(BeZero())
+
+	// test refused by default
+	g.Expect(r.guardMutatingCRDDeletion(ctx, gatekeeper, MutatingCRDs)).To(Equal(int32(1)))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning CRDDeletionBlocked")))
+	gatekeeper.Status.BlockedMutators = 1
+	g.Expect(r.guardMutatingCRDDeletion(ctx, gatekeeper, MutatingCRDs)).To(Equal(int32(1)))
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// test force
+	force := operatorv1alpha1.MutatingCRDDeletionForce
+	gatekeeper.Spec.MutatingCRDDeletion = &force
+	g.Expect(r.guardMutatingCRDDeletion(ctx, gatekeeper, MutatingCRDs)).To(BeZero())
+	archive, err := r.readPolicyArchive(ctx, gatekeeper, MutatorsArchiveName)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(archive).To(BeNil())
+
+	// test backup
+	backup := operatorv1alpha1.MutatingCRDDeletionBackup
+	gatekeeper.Spec.MutatingCRDDeletion = &backup
+	g.Expect(r.guardMutatingCRDDeletion(ctx, gatekeeper, MutatingCRDs)).To(BeZero())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal BackedUp Backed up 1 mutators")))
+	archive, err = r.readPolicyArchive(ctx, gatekeeper, MutatorsArchiveName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(archive.Items).To(HaveLen(1))
+	g.Expect(archive.Items[0].GetName()).To(Equal("set-owner"))
+	g.Expect(archive.Items[0].Object).ToNot(HaveKey("status"))
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+const migrationRetryInterval = 5 * time.Second
+
+// gatekeeperNamespace returns the namespace Gatekeeper is deployed to by the
+// current reconciliation.
+func (r *GatekeeperReconciler) gatekeeperNamespace() string {
+	if r.namespace != "" {
+		return r.namespace
+	}
+	return r.Namespace
+}
+
+// desiredNamespace returns the namespace Gatekeeper must be deployed to.
+func (r *GatekeeperReconciler) desiredNamespace(gatekeeper *operatorv1alpha1.Gatekeeper) string {
+	if gatekeeper.Spec.Namespace != "" {
+		return gatekeeper.Spec.Namespace
+	}
+	return r.Namespace
+}
+
+// deployedNamespace returns the namespace Gatekeeper was last completely
+// deployed to. Gatekeeper resources reconciled by operator releases that
+// predate spec.namespace are deployed to the default namespace.
+func (r *GatekeeperReconciler) deployedNamespace(gatekeeper *operatorv1alpha1.Gatekeeper) string {
+	if gatekeeper.Status.Namespace != "" {
+		return gatekeeper.Status.Namespace
+	}
+	return r.Namespace
+}
+
+// migrationSource returns the namespace Gatekeeper is being migrated from, or
+// an empty string if Gatekeeper is not being migrated.
+func (r *GatekeeperReconciler) migrationSource(gatekeeper *operatorv1alpha1.Gatekeeper) string {
+	if source := r.deployedNamespace(gatekeeper); source != r.gatekeeperNamespace() {
+		return source
+	}
+	return ""
+}
+
+// exemptMigrationTarget exempts the namespace Gatekeeper is migrated to in the
+// webhook deployment of the namespace it is migrated from, since Gatekeeper
+// only admits the admission.gatekeeper.sh/ignore label on exempt namespaces.
+// It returns whether the webhook deployment is still rolling out.
+func (r *GatekeeperReconciler) exemptMigrationTarget(ctx context.Context, source string) (bool, error) {
+	deployment := &unstructured.Unstructured{}
+	deployment.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	namespacedName := types.NamespacedName{Namespace: source, Name: WebhookDeploymentName}
+	if err := r.Get(ctx, namespacedName, deployment); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "Error attempting to get resource %s", namespacedName)
+	}
+
+	exempt := false
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok || container["name"] != managerContainer {
+			continue
+		}
+		args, _, _ := unstructured.NestedStringSlice(container, "args")
+		for _, arg := range args {
+			exempt = exempt || arg == util.ToArg(ExemptNamespaceArg, r.gatekeeperNamespace())
+		}
+	}
+	if !exempt {
+		if err := setContainerArg(deployment, managerContainer, ExemptNamespaceArg, r.gatekeeperNamespace(), true); err != nil {
+			return false, err
+		}
+		if err := r.Update(ctx, deployment); err != nil {
+			return false, errors.Wrapf(err, "Error attempting to update resource %s", namespacedName)
+		}
+		r.Log.Info("Exempted the namespace Gatekeeper is migrated to", "deployment", namespacedName, "namespace", r.gatekeeperNamespace())
+		return true, nil
+	}
+
+	generation := deployment.GetGeneration()
+	observedGeneration, _, _ := unstructured.NestedInt64(deployment.Object, "status", "observedGeneration")
+	replicas, _, _ := unstructured.NestedInt64(deployment.Object, "status", "replicas")
+	updatedReplicas, _, _ := unstructured.NestedInt64(deployment.Object, "status", "updatedReplicas")
+	return observedGeneration < generation || updatedReplicas != replicas, nil
+}
+
+// migrationOverrides keeps Gatekeeper running in the namespace it is migrated
+// from until the migration completes: the namespace stays exempt, the
+// Gatekeeper service account in it keeps its cluster role, and the webhook
+// server certificate Secret is seeded with its CA so that the certificate
+// rotation in both namespaces injects the same CA bundle into the webhook
+// configurations.
+func (r *GatekeeperReconciler) migrationOverrides(ctx context.Context, asset string, obj *unstructured.Unstructured, source string) error {
+	switch asset {
+	case WebhookFile:
+		return setContainerArg(obj, managerContainer, ExemptNamespaceArg, source, true)
+	case ClusterRoleBindingFile:
+		subjects, _, err := unstructured.NestedSlice(obj.Object, "subjects")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to retrieve subjects from roleBinding")
+		}
+		for _, s := range subjects {
+			subject, ok := runtime.DeepCopyJSONValue(s).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if err := unstructured.SetNestedField(subject, source, "namespace"); err != nil {
+				return errors.Wrapf(err, "Failed to set namespace for rolebinding subject")
+			}
+			subjects = append(subjects, subject)
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, subjects, "subjects"); err != nil {
+			return errors.Wrapf(err, "Failed to set updated subjects in rolebinding")
+		}
+	case ServerCertFile:
+		data, err := r.getWebhookServerCertData(ctx, r.gatekeeperNamespace(), obj.GetName())
+		if err != nil || len(data) > 0 {
+			return err
+		}
+		sourceData, err := r.getWebhookServerCertData(ctx, source, obj.GetName())
+		if err != nil {
+			return err
+		}
+		encoded := map[string]interface{}{}
+		for _, k := range []string{CACertName, CAKeyName} {
+			if len(sourceData[k]) > 0 {
+				encoded[k] = base64.StdEncoding.EncodeToString(sourceData[k])
+			}
+		}
+		if len(encoded) == 0 {
+			return nil
+		}
+		if err := unstructured.SetNestedMap(obj.Object, encoded, "data"); err != nil {
+			return errors.Wrapf(err, "Failed to set webhook server certificate data")
+		}
+	}
+	return nil
+}
+
+// teardownMigrationSource deletes the Gatekeeper resources from the namespace
+// Gatekeeper was migrated from, once the webhook configurations point to the
+// new namespace, and moves the policy archives and the captured profiles to
+// the new namespace. The namespace itself is kept, but no longer labeled as
+// exempt from Gatekeeper admission since it is no longer exempt.
+func (r *GatekeeperReconciler) teardownMigrationSource(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, source string) error {
+	if err := r.moveArchives(ctx, source); err != nil {
+		return err
+	}
+	if err := r.moveProfiles(ctx, gatekeeper, source); err != nil {
+		return err
+	}
+
+	applyMonitoringAssets, deleteMonitoringAssets := getMonitoringAssets(gatekeeper, r.isOpenShift(), r.PlatformInfo.HasPrometheusOperator())
+	assets := append(applyMonitoringAssets, deleteMonitoringAssets...)
+	for i := len(orderedStaticAssets) - 1; i >= 0; i-- {
+		assets = append(assets, orderedStaticAssets[i])
+	}
+
+	var namespacedAssets []string
+	for _, asset := range assets {
+		obj, err := util.GetManifestObject(asset)
+		if err != nil {
+			return err
+		}
+		if obj.GetNamespace() != "" {
+			namespacedAssets = append(namespacedAssets, asset)
+		}
+	}
+	if err := r.teardownAssets(ctx, namespacedAssets, source, gatekeeper); err != nil {
+		return err
+	}
+	for _, obj := range r.webhookExposureObjects(source) {
+		if err := r.crudResource(ctx, webhookExposureAsset(obj), obj, gatekeeper, delete); err != nil {
+			return err
+		}
+	}
+	return r.removeIgnoreLabel(ctx, source)
+}
+
+// removeIgnoreLabel removes the admission.gatekeeper.sh/ignore label the
+// operator set on the given namespace Gatekeeper was migrated from, since
+// Gatekeeper no longer exempts it. A label set to another value by someone
+// else is kept.
+func (r *GatekeeperReconciler) removeIgnoreLabel(ctx context.Context, source string) error {
+	manifest, err := util.GetManifestObject(NamespaceFile)
+	if err != nil {
+		return err
+	}
+	namespace := &unstructured.Unstructured{}
+	namespace.SetGroupVersionKind(manifest.GroupVersionKind())
+	if err := r.Get(ctx, types.NamespacedName{Name: source}, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "Error attempting to get resource %s", source)
+	}
+	if value, ok := namespace.GetLabels()[IgnoreLabel]; !ok || value != manifest.GetLabels()[IgnoreLabel] {
+		return nil
+	}
+	unstructured.RemoveNestedField(namespace.Object, "metadata", "labels", IgnoreLabel)
+	if err := r.Update(ctx, namespace); err != nil {
+		return errors.Wrapf(err, "Error attempting to update resource %s", source)
+	}
+	r.Log.Info("Removed the ignore label from the namespace Gatekeeper was migrated from", "namespace", source)
+	return nil
+}
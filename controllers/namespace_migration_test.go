@@ -0,0 +1,226 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+const movedNamespace = "moved-gatekeeper-system"
+
+func TestMigrationSource(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{}
+	r := &GatekeeperReconciler{Namespace: namespace}
+
+	// test default namespace
+	r.namespace = r.desiredNamespace(gatekeeper)
+	g.Expect(r.gatekeeperNamespace()).To(Equal(namespace))
+	g.Expect(r.deployedNamespace(gatekeeper)).To(Equal(namespace))
+	g.Expect(r.migrationSource(gatekeeper)).To(BeEmpty())
+
+	// test migration from the default namespace
+	gatekeeper.Spec.Namespace = movedNamespace
+	r.namespace = r.desiredNamespace(gatekeeper)
+	g.Expect(r.gatekeeperNamespace()).To(Equal(movedNamespace))
+	g.Expect(r.migrationSource(gatekeeper)).To(Equal(namespace))
+
+	// test migration completed
+	gatekeeper.Status.Namespace = movedNamespace
+	g.Expect(r.deployedNamespace(gatekeeper)).To(Equal(movedNamespace))
+	g.Expect(r.migrationSource(gatekeeper)).To(BeEmpty())
+}
+
+func TestExemptMigrationTarget(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+
+	deployment, err := util.GetManifestObject(WebhookFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	deployment.SetNamespace(namespace)
+	deployment.SetGeneration(1)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(deployment).Build()
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace, namespace: movedNamespace}
+
+	// test the new namespace is exempted
+	g.Expect(r.exemptMigrationTarget(ctx, namespace)).To(BeTrue())
+	updated := &unstructured.Unstructured{}
+	updated.SetGroupVersionKind(deployment.GroupVersionKind())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(deployment), updated)).To(Succeed())
+	g.Expect(getContainerArgumentsSlice(g, managerContainer, updated)).To(ContainElement(util.ToArg(ExemptNamespaceArg, movedNamespace)))
+
+	// test waiting for the rollout
+	g.Expect(r.exemptMigrationTarget(ctx, namespace)).To(BeTrue())
+	g.Expect(unstructured.SetNestedField(updated.Object, updated.GetGeneration(), "status", "observedGeneration")).To(Succeed())
+	g.Expect(c.Status().Update(ctx, updated)).To(Succeed())
+	g.Expect(r.exemptMigrationTarget(ctx, namespace)).To(BeFalse())
+
+	// test no deployment to exempt from
+	g.Expect(r.exemptMigrationTarget(ctx, "other-namespace")).To(BeFalse())
+}
+
+func TestMigrationOverrides(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: WebhookServerCertSecretName},
+		Data: map[string][]byte{
+			CACertName: []byte("ca-cert"),
+			CAKeyName:  []byte("ca-key"),
+			CertName:   []byte("cert"),
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sourceSecret).Build()
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace, namespace: movedNamespace}
+
+	// test the namespace Gatekeeper is migrated from stays exempt
+	webhookDeployment, err := util.GetManifestObject(WebhookFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.migrationOverrides(ctx, WebhookFile, webhookDeployment, namespace)).To(Succeed())
+	g.Expect(getContainerArgumentsSlice(g, managerContainer, webhookDeployment)).To(ContainElement(util.ToArg(ExemptNamespaceArg, namespace)))
+
+	// test the service account keeps its cluster role
+	clusterRoleBinding, err := util.GetManifestObject(ClusterRoleBindingFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(crOverrides(&operatorv1alpha1.Gatekeeper{}, ClusterRoleBindingFile, clusterRoleBinding, movedNamespace, false, false)).To(Succeed())
+	g.Expect(r.migrationOverrides(ctx, ClusterRoleBindingFile, clusterRoleBinding, namespace)).To(Succeed())
+	subjects, _, err := unstructured.NestedSlice(clusterRoleBinding.Object, "subjects")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(subjects).To(HaveLen(2))
+	g.Expect(subjects[0]).To(HaveKeyWithValue("namespace", movedNamespace))
+	g.Expect(subjects[1]).To(HaveKeyWithValue("namespace", namespace))
+
+	// test the certificate Secret is seeded with the CA
+	serverCert, err := util.GetManifestObject(ServerCertFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.migrationOverrides(ctx, ServerCertFile, serverCert, namespace)).To(Succeed())
+	data, _, err := unstructured.NestedStringMap(serverCert.Object, "data")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(Equal(map[string]string{
+		CACertName: "Y2EtY2VydA==",
+		CAKeyName:  "Y2Eta2V5",
+	}))
+
+	// test the certificate Secret is not seeded once populated
+	movedSecret := sourceSecret.DeepCopy()
+	movedSecret.SetNamespace(movedNamespace)
+	movedSecret.SetResourceVersion("")
+	g.Expect(c.Create(ctx, movedSecret)).To(Succeed())
+	serverCert, err = util.GetManifestObject(ServerCertFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(r.migrationOverrides(ctx, ServerCertFile, serverCert, namespace)).To(Succeed())
+	g.Expect(serverCert.Object).ToNot(HaveKey("data"))
+}
+
+func TestTeardownMigrationSource(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	var objs []client.Object
+	for _, asset := range []string{WebhookFile, RoleFile, ClusterRoleFile} {
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(setNamespace(obj, asset, namespace)).To(Succeed())
+		objs = append(objs, obj)
+	}
+	archive := newArchiveChunk(operatorv1alpha1.BackupStorageSecret, namespace, archiveChunkName("nightly", 0), "nightly")
+	g.Expect(setArchiveChunkData(archive, []byte("archive"))).To(Succeed())
+	profile := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      profileSecretName("gatekeeper-audit-1", "heap"),
+			Namespace: namespace,
+			Labels:    map[string]string{ProfileLabel: "heap"},
+		},
+		Data: map[string][]byte{"heap.pb.gz": []byte("profile")},
+	}
+	namespaceManifest, err := util.GetManifestObject(NamespaceFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	sourceNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: namespaceManifest.GetLabels()}}
+	g.Expect(sourceNamespace.Labels).To(HaveKey(IgnoreLabel))
+	objs = append(objs, archive, profile, sourceNamespace)
+	// A previous archive of the same name in the new namespace is replaced.
+	staleArchive := newArchiveChunk(operatorv1alpha1.BackupStorageConfigMap, movedNamespace, archiveChunkName("nightly", 1), "nightly")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objs, staleArchive)...).Build()
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace, namespace: movedNamespace}
+
+	gatekeeper := &operatorv1alpha1.Gatekeeper{ObjectMeta: metav1.ObjectMeta{Name: defaultGatekeeperCrName}}
+	g.Expect(r.teardownMigrationSource(ctx, gatekeeper, namespace)).To(Succeed())
+	for _, obj := range objs {
+		err := c.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, obj)
+		if obj.GetNamespace() != "" {
+			g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), obj.GetName())
+		} else {
+			g.Expect(err).ToNot(HaveOccurred(), obj.GetName())
+		}
+	}
+
+	// test the archives are moved to the new namespace
+	chunks, err := r.listArchiveChunks(ctx, movedNamespace, operatorv1alpha1.BackupStorageSecret, "nightly")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(chunks).To(HaveLen(1))
+	g.Expect(archiveChunkData(&chunks[0])).To(Equal([]byte("archive")))
+	chunks, err = r.listArchiveChunks(ctx, movedNamespace, operatorv1alpha1.BackupStorageConfigMap, "nightly")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(chunks).To(BeEmpty())
+
+	// test the profiles are moved to the new namespace
+	movedProfile := &corev1.Secret{}
+	g.Expect(c.Get(ctx, types.NamespacedName{Namespace: movedNamespace, Name: profile.Name}, movedProfile)).To(Succeed())
+	g.Expect(movedProfile.Labels).To(HaveKeyWithValue(ProfileLabel, "heap"))
+	g.Expect(movedProfile.Data).To(HaveKeyWithValue("heap.pb.gz", []byte("profile")))
+
+	// test the source namespace is kept, empty and no longer labeled as
+	// exempt
+	g.Expect(c.Get(ctx, types.NamespacedName{Name: namespace}, sourceNamespace)).To(Succeed())
+	g.Expect(sourceNamespace.Labels).ToNot(HaveKey(IgnoreLabel))
+	g.Expect(sourceNamespace.Labels).To(HaveKeyWithValue(SystemLabel, "yes"))
+	secrets := &corev1.SecretList{}
+	g.Expect(c.List(ctx, secrets, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(secrets.Items).To(BeEmpty())
+	configMaps := &corev1.ConfigMapList{}
+	g.Expect(c.List(ctx, configMaps, client.InNamespace(namespace))).To(Succeed())
+	g.Expect(configMaps.Items).To(BeEmpty())
+
+	// test an ignore label not set by the operator is kept
+	sourceNamespace.Labels[IgnoreLabel] = "exempt"
+	g.Expect(c.Update(ctx, sourceNamespace)).To(Succeed())
+	g.Expect(r.teardownMigrationSource(ctx, gatekeeper, namespace)).To(Succeed())
+	g.Expect(c.Get(ctx, types.NamespacedName{Name: namespace}, sourceNamespace)).To(Succeed())
+	g.Expect(sourceNamespace.Labels).To(HaveKeyWithValue(IgnoreLabel, "exempt"))
+}
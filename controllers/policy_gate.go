@@ -0,0 +1,212 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	ConditionReasonPolicyNotReady = "PolicyNotReady"
+
+	defaultPolicyReadyTimeout = 10 * time.Minute
+)
+
+var (
+	constraintTemplateListGVK          = schema.GroupVersionKind{Group: "templates.gatekeeper.sh", Version: "v1", Kind: "ConstraintTemplateList"}
+	constraintTemplatePodStatusListGVK = schema.GroupVersionKind{Group: "status.gatekeeper.sh", Version: "v1beta1", Kind: "ConstraintTemplatePodStatusList"}
+	constraintPodStatusListGVK         = schema.GroupVersionKind{Group: "status.gatekeeper.sh", Version: "v1beta1", Kind: "ConstraintPodStatusList"}
+)
+
+const constraintsGroupVersion = "constraints.gatekeeper.sh/v1beta1"
+
+func policyReadyTimeout(gate *operatorv1alpha1.WebhookPolicyReadyGateConfig) time.Duration {
+	if gate.Timeout != nil {
+		return gate.Timeout.Duration
+	}
+	return defaultPolicyReadyTimeout
+}
+
+// updatePolicyReadyGate returns a description of the policies that are not
+// yet enforced on all webhook pods, or an empty string if the failure policy
+// of the webhooks does not need to be kept at Ignore, and whether the gate
+// timed out. The gate waits for the policies once the webhook deployment is
+// ready again after it was failing open e.g. after install or upgrade.
+func (r *GatekeeperReconciler) updatePolicyReadyGate(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, deploymentFailOpen bool, now time.Time) (string, bool) {
+	var gate *operatorv1alpha1.WebhookPolicyReadyGateConfig
+	if gatekeeper.Spec.Webhook != nil {
+		gate = gatekeeper.Spec.Webhook.PolicyReadyGate
+	}
+	if gate == nil {
+		r.resetPolicyReadyGate(true)
+		return "", false
+	}
+	if deploymentFailOpen {
+		r.resetPolicyReadyGate(false)
+		return "", false
+	}
+	if r.policyReady {
+		return "", false
+	}
+	if r.policyGateSince.IsZero() {
+		r.policyGateSince = now
+	}
+
+	notReady, err := r.policiesNotReady(ctx)
+	if err != nil {
+		r.Log.Error(err, "Unable to check whether the policies are enforced on all webhook pods")
+		notReady = fmt.Sprintf("Unable to check whether the policies are enforced on all webhook pods: %v", err)
+	}
+	if notReady == "" {
+		r.Log.Info("Policies enforced on all webhook pods", "waited", now.Sub(r.policyGateSince))
+		r.resetPolicyReadyGate(true)
+		return "", false
+	}
+
+	timeout := policyReadyTimeout(gate)
+	timedOut := now.Sub(r.policyGateSince) >= timeout
+	if timedOut && !r.policyGateTimedOut {
+		r.policyGateTimedOut = true
+		r.Log.Info("Timed out waiting for the policies to be enforced on all webhook pods", "timeout", timeout, "reason", notReady)
+		r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonPolicyNotReady,
+			"%s after %s, keeping the webhook failure policy set to Ignore", notReady, timeout)
+	}
+	return notReady, timedOut
+}
+
+func (r *GatekeeperReconciler) resetPolicyReadyGate(policyReady bool) {
+	r.policyGateSince = time.Time{}
+	r.policyGateTimedOut = false
+	r.policyReady = policyReady
+}
+
+// policiesNotReady returns a description of the constraint templates and
+// constraints whose ConstraintTemplatePodStatus and ConstraintPodStatus do
+// not report them as enforced on every webhook pod, or an empty string if
+// all are.
+func (r *GatekeeperReconciler) policiesNotReady(ctx context.Context) (string, error) {
+	pods := &unstructured.UnstructuredList{}
+	pods.SetAPIVersion("v1")
+	pods.SetKind("PodList")
+	err := r.List(ctx, pods, client.InNamespace(r.gatekeeperNamespace()), client.MatchingLabels{
+		"control-plane":           "controller-manager",
+		"gatekeeper.sh/operation": "webhook",
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to list webhook pods in namespace %s", r.gatekeeperNamespace())
+	}
+	var podNames []string
+	for _, pod := range pods.Items {
+		if pod.GetDeletionTimestamp() == nil {
+			podNames = append(podNames, pod.GetName())
+		}
+	}
+
+	templates, err := r.listUnstructured(ctx, constraintTemplateListGVK, "")
+	if err != nil {
+		return "", err
+	}
+	// The probe constraint template of the prober is not a user policy.
+	templates = withoutProbeObjects(templates)
+	templateStatuses, err := r.listUnstructured(ctx, constraintTemplatePodStatusListGVK, r.gatekeeperNamespace())
+	if err != nil {
+		return "", err
+	}
+	constraintStatuses, err := r.listUnstructured(ctx, constraintPodStatusListGVK, r.gatekeeperNamespace())
+	if err != nil {
+		return "", err
+	}
+	templatesEnforced := podStatusIndex(templateStatuses, "templateUID", false)
+	constraintsEnforced := podStatusIndex(constraintStatuses, "constraintUID", true)
+
+	notReadyTemplates, notReadyConstraints, constraintCount := 0, 0, 0
+	for _, template := range templates {
+		if !enforcedOnPods(templatesEnforced, template, podNames) {
+			notReadyTemplates++
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(template.Object, "spec", "crd", "spec", "names", "kind")
+		constraints := &unstructured.UnstructuredList{}
+		constraints.SetAPIVersion(constraintsGroupVersion)
+		constraints.SetKind(kind + "List")
+		if err := r.List(ctx, constraints); err != nil {
+			return "", errors.Wrapf(err, "Unable to list %s constraints", kind)
+		}
+		for _, constraint := range constraints.Items {
+			constraintCount++
+			if !enforcedOnPods(constraintsEnforced, constraint, podNames) {
+				notReadyConstraints++
+			}
+		}
+	}
+	if notReadyTemplates == 0 && notReadyConstraints == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("%d of %d constraint templates and %d of %d constraints are not enforced on all %d webhook pods",
+		notReadyTemplates, len(templates), notReadyConstraints, constraintCount, len(podNames)), nil
+}
+
+func (r *GatekeeperReconciler) listUnstructured(ctx context.Context, gvk schema.GroupVersionKind, namespace string) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk)
+	if err := r.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrapf(err, "Unable to list %s", gvk.Kind)
+	}
+	return list.Items, nil
+}
+
+// podStatusIndex returns the set of "<pod>/<uid>" keys of the given pod
+// statuses that report the observed generation of the object with the given
+// UID as loaded without errors, and enforced if requireEnforced is set. The
+// observed generation of each key is returned as the value.
+func podStatusIndex(statuses []unstructured.Unstructured, uidField string, requireEnforced bool) map[string]int64 {
+	index := map[string]int64{}
+	for _, status := range statuses {
+		pod, _, _ := unstructured.NestedString(status.Object, "status", "id")
+		uid, _, _ := unstructured.NestedString(status.Object, "status", uidField)
+		generation, _, _ := unstructured.NestedInt64(status.Object, "status", "observedGeneration")
+		statusErrors, _, _ := unstructured.NestedSlice(status.Object, "status", "errors")
+		enforced, _, _ := unstructured.NestedBool(status.Object, "status", "enforced")
+		if len(statusErrors) > 0 || (requireEnforced && !enforced) {
+			continue
+		}
+		index[pod+"/"+uid] = generation
+	}
+	return index
+}
+
+// enforcedOnPods returns whether the current generation of the given object
+// is reported as enforced on all the given pods.
+func enforcedOnPods(index map[string]int64, obj unstructured.Unstructured, pods []string) bool {
+	for _, pod := range pods {
+		generation, ok := index[pod+"/"+string(obj.GetUID())]
+		if !ok || generation != obj.GetGeneration() {
+			return false
+		}
+	}
+	return true
+}
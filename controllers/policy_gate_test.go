@@ -0,0 +1,143 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+var constraintListGVK = schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabelsList"}
+
+func policyObject(gvk schema.GroupVersionKind, namespace, name, uid string, status map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind[:len(gvk.Kind)-len("List")]})
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetUID(types.UID(uid))
+	obj.SetGeneration(1)
+	if status != nil {
+		obj.Object["status"] = status
+	}
+	return obj
+}
+
+func TestUpdatePolicyReadyGate(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	for _, gvk := range []schema.GroupVersionKind{constraintTemplateListGVK, constraintTemplatePodStatusListGVK, constraintPodStatusListGVK, constraintListGVK} {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.UnstructuredList{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind[:len(gvk.Kind)-len("List")]), &unstructured.Unstructured{})
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gatekeeper-controller-manager-abc",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"control-plane":           "controller-manager",
+				"gatekeeper.sh/operation": "webhook",
+			},
+		},
+	}
+	template := policyObject(constraintTemplateListGVK, "", "k8srequiredlabels", "template-uid", nil)
+	g.Expect(unstructured.SetNestedField(template.Object, "K8sRequiredLabels", "spec", "crd", "spec", "names", "kind")).To(Succeed())
+	constraint := policyObject(constraintListGVK, "", "ns-must-have-owner", "constraint-uid", nil)
+	templateStatus := policyObject(constraintTemplatePodStatusListGVK, namespace, "abc-k8srequiredlabels", "", map[string]interface{}{
+		"id":                 pod.GetName(),
+		"templateUID":        "template-uid",
+		"observedGeneration": int64(1),
+	})
+	constraintStatus := policyObject(constraintPodStatusListGVK, namespace, "abc-ns-must-have-owner", "", map[string]interface{}{
+		"id":                 pod.GetName(),
+		"constraintUID":      "constraint-uid",
+		"observedGeneration": int64(1),
+		"enforced":           false,
+	})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod, template, constraint, templateStatus).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &GatekeeperReconciler{
+		Client:    c,
+		Log:       ctrl.Log,
+		Scheme:    scheme,
+		Namespace: namespace,
+		Recorder:  recorder,
+	}
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Webhook: &operatorv1alpha1.WebhookConfig{
+				PolicyReadyGate: &operatorv1alpha1.WebhookPolicyReadyGateConfig{
+					Timeout: &metav1.Duration{Duration: time.Minute},
+				},
+			},
+		},
+	}
+	now := time.Now()
+
+	// test deployment pending
+	notReady, timedOut := r.updatePolicyReadyGate(ctx, gatekeeper, true, now)
+	g.Expect(notReady).To(BeEmpty())
+	g.Expect(timedOut).To(BeFalse())
+
+	// test constraint status missing
+	notReady, timedOut = r.updatePolicyReadyGate(ctx, gatekeeper, false, now)
+	g.Expect(notReady).To(Equal("0 of 1 constraint templates and 1 of 1 constraints are not enforced on all 1 webhook pods"))
+	g.Expect(timedOut).To(BeFalse())
+
+	// test constraint not enforced and timed out
+	g.Expect(c.Create(ctx, constraintStatus)).To(Succeed())
+	notReady, timedOut = r.updatePolicyReadyGate(ctx, gatekeeper, false, now.Add(time.Minute))
+	g.Expect(notReady).ToNot(BeEmpty())
+	g.Expect(timedOut).To(BeTrue())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning PolicyNotReady")))
+	_, timedOut = r.updatePolicyReadyGate(ctx, gatekeeper, false, now.Add(2*time.Minute))
+	g.Expect(timedOut).To(BeTrue())
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// test enforced
+	g.Expect(unstructured.SetNestedField(constraintStatus.Object, true, "status", "enforced")).To(Succeed())
+	g.Expect(c.Update(ctx, constraintStatus)).To(Succeed())
+	notReady, timedOut = r.updatePolicyReadyGate(ctx, gatekeeper, false, now.Add(3*time.Minute))
+	g.Expect(notReady).To(BeEmpty())
+	g.Expect(timedOut).To(BeFalse())
+
+	// test template updated after the gate passed
+	template.SetGeneration(2)
+	g.Expect(c.Update(ctx, template)).To(Succeed())
+	notReady, _ = r.updatePolicyReadyGate(ctx, gatekeeper, false, now.Add(4*time.Minute))
+	g.Expect(notReady).To(BeEmpty())
+
+	// test upgrade waits for the new template generation
+	r.updatePolicyReadyGate(ctx, gatekeeper, true, now.Add(5*time.Minute))
+	notReady, _ = r.updatePolicyReadyGate(ctx, gatekeeper, false, now.Add(6*time.Minute))
+	g.Expect(notReady).To(HavePrefix("1 of 1 constraint templates"))
+}
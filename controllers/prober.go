@@ -0,0 +1,382 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	// ProbeLabel is set on the canary objects of the webhook probes.
+	ProbeLabel = "operator.gatekeeper.sh/probe"
+	// ProbeMutatedLabel is set by the probe mutator on the canary of the
+	// mutating webhook probe.
+	ProbeMutatedLabel = "operator.gatekeeper.sh/probe-mutated"
+	// ProbeDeniedLabel is set on the canary of the validation webhook probe,
+	// which the probe constraint denies.
+	ProbeDeniedLabel = "operator.gatekeeper.sh/probe-denied"
+	// IgnoreLabel is the label Gatekeeper only allows on exempt namespaces.
+	IgnoreLabel = "admission.gatekeeper.sh/ignore"
+
+	probeNamespaceName   = "gatekeeper-operator-probe"
+	probeMutatorName     = "gatekeeper-operator-probe"
+	probeMutatorAsset    = "probe-mutator"
+	probeTemplateName    = "gatekeeperoperatorprobe"
+	probeTemplateKind    = "GatekeeperOperatorProbe"
+	probeTemplateAsset   = "probe-constraint-template"
+	probeConstraintName  = "gatekeeper-operator-probe"
+	probeConstraintAsset = "probe-constraint"
+	defaultProbeInterval = time.Minute
+	defaultProbeTimeout  = 5 * time.Second
+)
+
+// gatekeeperDenialRegexp matches the errors of the admission requests denied
+// by a Gatekeeper webhook.
+var gatekeeperDenialRegexp = regexp.MustCompile(`admission webhook "[^"]+\.gatekeeper\.sh" denied the request`)
+
+func webhookProber(gatekeeper *operatorv1alpha1.Gatekeeper) *operatorv1alpha1.WebhookProberConfig {
+	if gatekeeper.Spec.Webhook == nil {
+		return nil
+	}
+	return gatekeeper.Spec.Webhook.Prober
+}
+
+// webhookProbeInterval returns the interval between the probes of the
+// webhooks, or zero if the prober is disabled.
+func webhookProbeInterval(gatekeeper *operatorv1alpha1.Gatekeeper) time.Duration {
+	prober := webhookProber(gatekeeper)
+	if prober == nil {
+		return 0
+	}
+	if prober.Interval != nil && prober.Interval.Duration > 0 {
+		return prober.Interval.Duration
+	}
+	return defaultProbeInterval
+}
+
+func webhookProbeTimeout(prober *operatorv1alpha1.WebhookProberConfig) time.Duration {
+	if prober.Timeout != nil && prober.Timeout.Duration > 0 {
+		return prober.Timeout.Duration
+	}
+	return defaultProbeTimeout
+}
+
+// probeWebhooks probes the enabled webhooks with dry-run creates of canary
+// namespaces through the API server if the probe interval elapsed since the
+// previous probe. It returns the results of the probes, or nil if the
+// webhooks were not probed.
+//
+// The Gatekeeper namespace is exempt from the webhooks, hence canary
+// namespaces are used rather than objects in the Gatekeeper namespace. The
+// webhooks may have the Ignore failure policy, so a probe only succeeds if the
+// webhook proves that it answered. The check-ignore-label webhook is expected
+// to deny the canary carrying the ignore label since only exempt namespaces
+// may have it. The validation webhook is expected to deny its canary through
+// the probe constraint, and the mutating webhook to label its canary through
+// the probe mutator, both managed by the operator.
+func (r *GatekeeperReconciler) probeWebhooks(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, now time.Time) []operatorv1alpha1.WebhookProbeStatus {
+	interval := webhookProbeInterval(gatekeeper)
+	validating := interval > 0 && (gatekeeper.Spec.ValidatingWebhook == nil || *gatekeeper.Spec.ValidatingWebhook == operatorv1alpha1.WebhookEnabled)
+	mutating := interval > 0 && mutatingWebhookEnabled(gatekeeper.Spec.MutatingWebhook)
+	if !validating && !r.probeConstraintDeleted {
+		r.probeConstraintDeleted = r.deleteProbeObjects(ctx, gatekeeper, probeConstraintObjects())
+	}
+	if !mutating && !r.probeMutatorDeleted {
+		r.probeMutatorDeleted = r.deleteProbeObjects(ctx, gatekeeper, probeMutatorObjects())
+	}
+	if interval == 0 {
+		r.lastWebhookProbe = time.Time{}
+		return nil
+	}
+	if !r.lastWebhookProbe.IsZero() && now.Sub(r.lastWebhookProbe) < interval {
+		return nil
+	}
+	r.lastWebhookProbe = now
+	timeout := webhookProbeTimeout(webhookProber(gatekeeper))
+
+	probes := []operatorv1alpha1.WebhookProbeStatus{}
+	if validating {
+		probes = append(probes, r.probeWebhook(ctx, CheckIgnoreLabelGatekeeperWebhook, timeout))
+		r.probeConstraintDeleted = false
+		probes = append(probes, r.probeWebhookWithObjects(ctx, gatekeeper, ValidationGatekeeperWebhook, probeConstraintObjects(), now, timeout)...)
+	}
+	if mutating {
+		r.probeMutatorDeleted = false
+		probes = append(probes, r.probeWebhookWithObjects(ctx, gatekeeper, MutationGatekeeperWebhook, probeMutatorObjects(), now, timeout)...)
+	}
+	for _, probe := range probes {
+		recordWebhookProbe(probe)
+		if !probe.Succeeded {
+			r.Log.Info("Webhook probe failed", "webhook", probe.Webhook, "latency", probe.Latency.Duration, "message", probe.Message)
+		}
+	}
+	return probes
+}
+
+// probeWebhookWithObjects applies the given probe objects the webhook needs
+// to answer its probe and probes it if they already existed. Gatekeeper
+// loads new policies asynchronously, so the webhook is first probed at the
+// interval following their creation.
+func (r *GatekeeperReconciler) probeWebhookWithObjects(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, webhook string, objs []probeObject, now time.Time, timeout time.Duration) []operatorv1alpha1.WebhookProbeStatus {
+	existed, err := r.applyProbeObjects(ctx, gatekeeper, objs)
+	switch {
+	case err != nil:
+		return []operatorv1alpha1.WebhookProbeStatus{{
+			Webhook:       webhook,
+			LastProbeTime: metav1.NewTime(now),
+			Message:       fmt.Sprintf("Unable to apply the probe policies: %v", err),
+		}}
+	case existed:
+		return []operatorv1alpha1.WebhookProbeStatus{r.probeWebhook(ctx, webhook, timeout)}
+	default:
+		r.Log.Info("Created the probe policies, the webhook is probed at the next interval", "webhook", webhook)
+		return nil
+	}
+}
+
+// probeObject is an object managed by the prober for a webhook to answer its
+// probe.
+type probeObject struct {
+	asset string
+	obj   *unstructured.Unstructured
+}
+
+func probeMutatorObjects() []probeObject {
+	return []probeObject{{asset: probeMutatorAsset, obj: probeMutator()}}
+}
+
+// probeConstraintObjects returns the probe constraint template and its
+// constraint. The constraint is applied after and deleted before the
+// template, whose CRD Gatekeeper creates and deletes along with it.
+func probeConstraintObjects() []probeObject {
+	return []probeObject{
+		{asset: probeTemplateAsset, obj: probeConstraintTemplate()},
+		{asset: probeConstraintAsset, obj: probeConstraint()},
+	}
+}
+
+// probeMutator returns the mutator setting the ProbeMutatedLabel on the
+// canary of the mutating webhook probe.
+func probeMutator() *unstructured.Unstructured {
+	mutator := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"match": map[string]interface{}{
+				"scope": "Cluster",
+				"kinds": []interface{}{
+					map[string]interface{}{
+						"apiGroups": []interface{}{""},
+						"kinds":     []interface{}{"Namespace"},
+					},
+				},
+				"labelSelector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						ProbeLabel: "true",
+					},
+				},
+			},
+			"location": fmt.Sprintf("metadata.labels.%q", ProbeMutatedLabel),
+			"parameters": map[string]interface{}{
+				"assign": map[string]interface{}{
+					"value": "true",
+				},
+			},
+		},
+	}}
+	mutator.SetAPIVersion("mutations.gatekeeper.sh/v1")
+	mutator.SetKind("AssignMetadata")
+	mutator.SetName(probeMutatorName)
+	mutator.SetLabels(map[string]string{ProbeLabel: "true"})
+	return mutator
+}
+
+// probeConstraintTemplate returns the constraint template denying every
+// object matched by its constraints.
+func probeConstraintTemplate() *unstructured.Unstructured {
+	template := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"crd": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"names": map[string]interface{}{
+						"kind": probeTemplateKind,
+					},
+				},
+			},
+			"targets": []interface{}{
+				map[string]interface{}{
+					"target": "admission.k8s.gatekeeper.sh",
+					"rego": fmt.Sprintf(`package %s
+
+violation[{"msg": msg}] {
+  msg := "Denied the webhook probe of the Gatekeeper operator"
+}
+`, probeTemplateName),
+				},
+			},
+		},
+	}}
+	template.SetAPIVersion("templates.gatekeeper.sh/v1")
+	template.SetKind("ConstraintTemplate")
+	template.SetName(probeTemplateName)
+	template.SetLabels(map[string]string{ProbeLabel: "true"})
+	return template
+}
+
+// probeConstraint returns the constraint denying the canary of the validation
+// webhook probe.
+func probeConstraint() *unstructured.Unstructured {
+	constraint := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"enforcementAction": "deny",
+			"match": map[string]interface{}{
+				"kinds": []interface{}{
+					map[string]interface{}{
+						"apiGroups": []interface{}{""},
+						"kinds":     []interface{}{"Namespace"},
+					},
+				},
+				"labelSelector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						ProbeDeniedLabel: "true",
+					},
+				},
+			},
+		},
+	}}
+	constraint.SetAPIVersion(constraintsGroupVersion)
+	constraint.SetKind(probeTemplateKind)
+	constraint.SetName(probeConstraintName)
+	constraint.SetLabels(map[string]string{ProbeLabel: "true"})
+	return constraint
+}
+
+// applyProbeObjects applies the given probe objects in order and returns
+// whether they all already existed. The probe objects are not ready if an
+// object of a kind that is not served yet, e.g. a constraint whose CRD
+// Gatekeeper did not create yet, is pending.
+func (r *GatekeeperReconciler) applyProbeObjects(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, objs []probeObject) (bool, error) {
+	existed := true
+	for _, o := range objs {
+		clusterObj := &unstructured.Unstructured{}
+		clusterObj.SetGroupVersionKind(o.obj.GroupVersionKind())
+		err := r.Get(ctx, client.ObjectKeyFromObject(o.obj), clusterObj)
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, errors.Wrapf(err, "Unable to get %s %s", o.obj.GetKind(), o.obj.GetName())
+		}
+		existed = existed && err == nil
+		if err := r.crudResource(ctx, o.asset, o.obj, gatekeeper, apply); err != nil {
+			return false, err
+		}
+	}
+	return existed, nil
+}
+
+// deleteProbeObjects deletes the given probe objects in reverse order if they
+// exist and returns whether they are all deleted. Errors are only logged since
+// the probe objects only match the canaries.
+func (r *GatekeeperReconciler) deleteProbeObjects(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, objs []probeObject) bool {
+	for i := len(objs) - 1; i >= 0; i-- {
+		o := objs[i]
+		err := r.crudResource(ctx, o.asset, o.obj, gatekeeper, delete)
+		if err != nil && !meta.IsNoMatchError(errors.Cause(err)) {
+			r.Log.Error(err, "Unable to delete the probe policy", "kind", o.obj.GetKind(), "name", o.obj.GetName())
+			return false
+		}
+	}
+	return true
+}
+
+// withoutProbeObjects returns the given objects except the ones created by
+// the prober, which are not user data.
+func withoutProbeObjects(objs []unstructured.Unstructured) []unstructured.Unstructured {
+	filtered := objs[:0]
+	for _, obj := range objs {
+		if obj.GetLabels()[ProbeLabel] != "true" {
+			filtered = append(filtered, obj)
+		}
+	}
+	return filtered
+}
+
+// probeWebhook probes the given webhook with a dry-run create of its canary.
+// The mutating webhook is expected to mutate its canary, the other webhooks
+// to deny it.
+func (r *GatekeeperReconciler) probeWebhook(ctx context.Context, webhook string, timeout time.Duration) operatorv1alpha1.WebhookProbeStatus {
+	canary := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: probeNamespaceName,
+			Labels: map[string]string{
+				ProbeLabel: "true",
+			},
+		},
+	}
+	expectDenial := true
+	switch webhook {
+	case CheckIgnoreLabelGatekeeperWebhook:
+		canary.Labels[IgnoreLabel] = "probe"
+	case ValidationGatekeeperWebhook:
+		canary.Labels[ProbeDeniedLabel] = "true"
+	default:
+		expectDenial = false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := r.Create(ctx, canary, client.DryRunAll)
+	latency := time.Since(start)
+
+	probe := operatorv1alpha1.WebhookProbeStatus{
+		Webhook:       webhook,
+		Latency:       metav1.Duration{Duration: latency},
+		LastProbeTime: metav1.NewTime(start),
+	}
+	denied := err != nil && gatekeeperDenialRegexp.MatchString(err.Error())
+	switch {
+	case latency >= timeout:
+		probe.Message = fmt.Sprintf("The probe did not complete within %s", timeout)
+	case err != nil && !denied:
+		probe.Message = fmt.Sprintf("The probe failed: %v", err)
+	case expectDenial && !denied:
+		probe.Message = "The probe was admitted, the webhook did not answer"
+	case expectDenial && !strings.Contains(err.Error(), fmt.Sprintf("admission webhook %q", webhook)):
+		probe.Message = fmt.Sprintf("The probe was not denied by the webhook: %v", err)
+	case !expectDenial && denied:
+		probe.Message = fmt.Sprintf("The probe was denied: %v", err)
+	case !expectDenial && canary.Labels[ProbeMutatedLabel] != "true":
+		probe.Message = "The probe was not mutated, the webhook did not answer"
+	default:
+		probe.Succeeded = true
+	}
+	return probe
+}
@@ -0,0 +1,187 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+func TestProbeWebhooks(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	// checkIgnoreLabelErr, validationErr and mutationErr are returned for the
+	// dry-run creates of the canaries of each webhook, and mutated is whether
+	// the canary of the mutating webhook is mutated. probeObjectGets counts
+	// the gets of the probe objects.
+	var checkIgnoreLabelErr, validationErr, mutationErr error
+	var dryRun, mutated bool
+	probeObjectGets := 0
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if _, ok := obj.(*unstructured.Unstructured); ok {
+				probeObjectGets++
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*corev1.Namespace); !ok {
+				return c.Create(ctx, obj, opts...)
+			}
+			createOpts := &client.CreateOptions{}
+			createOpts.ApplyOptions(opts)
+			dryRun = len(createOpts.DryRun) == 1 && createOpts.DryRun[0] == metav1.DryRunAll
+			g.Expect(obj.GetLabels()).To(HaveKeyWithValue(ProbeLabel, "true"))
+			if _, ok := obj.GetLabels()[IgnoreLabel]; ok {
+				return checkIgnoreLabelErr
+			}
+			if _, ok := obj.GetLabels()[ProbeDeniedLabel]; ok {
+				return validationErr
+			}
+			if mutated {
+				obj.GetLabels()[ProbeMutatedLabel] = "true"
+			}
+			return mutationErr
+		},
+	}).Build()
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme}
+	gatekeeper := &operatorv1alpha1.Gatekeeper{ObjectMeta: metav1.ObjectMeta{Name: defaultGatekeeperCrName}}
+	now := time.Now()
+	denial := func(webhook, message string) error {
+		return &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: fmt.Sprintf("admission webhook %q denied the request: %s", webhook, message),
+		}}
+	}
+
+	// test disabled
+	g.Expect(r.probeWebhooks(ctx, gatekeeper, now)).To(BeNil())
+	g.Expect(probeObjectGets).To(Equal(3))
+	// test the probe objects are only deleted once
+	g.Expect(r.probeWebhooks(ctx, gatekeeper, now)).To(BeNil())
+	g.Expect(probeObjectGets).To(Equal(3))
+
+	// test the validation and mutating webhooks are not probed until the
+	// probe objects exist
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		Prober: &operatorv1alpha1.WebhookProberConfig{},
+	}
+	checkIgnoreLabelErr = denial(CheckIgnoreLabelGatekeeperWebhook, fmt.Sprintf("Only exempt namespace can have the %s label", IgnoreLabel))
+	validationErr = denial(ValidationGatekeeperWebhook, "[gatekeeper-operator-probe] Denied the webhook probe of the Gatekeeper operator")
+	probes := r.probeWebhooks(ctx, gatekeeper, now)
+	g.Expect(dryRun).To(BeTrue())
+	g.Expect(probes).To(HaveLen(1))
+	g.Expect(probes[0].Webhook).To(Equal(CheckIgnoreLabelGatekeeperWebhook))
+	g.Expect(probes[0].Succeeded).To(BeTrue(), probes[0].Message)
+	mutator := probeMutator()
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(mutator), mutator)).To(Succeed())
+	g.Expect(mutator.Object["spec"]).To(HaveKeyWithValue("location", `metadata.labels."operator.gatekeeper.sh/probe-mutated"`))
+	template := probeConstraintTemplate()
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(template), template)).To(Succeed())
+	constraint := probeConstraint()
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(constraint), constraint)).To(Succeed())
+
+	// test succeeded
+	mutated = true
+	probes = r.probeWebhooks(ctx, gatekeeper, now.Add(defaultProbeInterval))
+	g.Expect(probes).To(HaveLen(3))
+	for _, probe := range probes {
+		g.Expect(probe.Succeeded).To(BeTrue(), probe.Message)
+		g.Expect(testutil.ToFloat64(webhookProbeSuccess.WithLabelValues(probe.Webhook))).To(Equal(1.0))
+	}
+
+	// test interval not elapsed
+	g.Expect(r.probeWebhooks(ctx, gatekeeper, now.Add(defaultProbeInterval+time.Second))).To(BeNil())
+
+	// test the probes are admitted without answer of the webhooks
+	checkIgnoreLabelErr, validationErr = nil, nil
+	mutated = false
+	probes = r.probeWebhooks(ctx, gatekeeper, now.Add(2*defaultProbeInterval))
+	g.Expect(probes).To(HaveLen(3))
+	g.Expect(probes[0].Webhook).To(Equal(CheckIgnoreLabelGatekeeperWebhook))
+	g.Expect(probes[0].Succeeded).To(BeFalse())
+	g.Expect(probes[0].Message).To(ContainSubstring("the webhook did not answer"))
+	g.Expect(probes[1].Webhook).To(Equal(ValidationGatekeeperWebhook))
+	g.Expect(probes[1].Succeeded).To(BeFalse())
+	g.Expect(probes[1].Message).To(ContainSubstring("the webhook did not answer"))
+	g.Expect(probes[2].Webhook).To(Equal(MutationGatekeeperWebhook))
+	g.Expect(probes[2].Succeeded).To(BeFalse())
+	g.Expect(probes[2].Message).To(Equal("The probe was not mutated, the webhook did not answer"))
+	g.Expect(testutil.ToFloat64(webhookProbeSuccess.WithLabelValues(MutationGatekeeperWebhook))).To(Equal(0.0))
+
+	// test failed
+	checkIgnoreLabelErr = denial(ValidationGatekeeperWebhook, "namespaces must have an owner")
+	validationErr = apierrors.NewInternalError(fmt.Errorf("failed calling webhook %q: context deadline exceeded", ValidationGatekeeperWebhook))
+	mutationErr = apierrors.NewInternalError(fmt.Errorf("failed calling webhook %q: context deadline exceeded", MutationGatekeeperWebhook))
+	probes = r.probeWebhooks(ctx, gatekeeper, now.Add(3*defaultProbeInterval))
+	g.Expect(probes).To(HaveLen(3))
+	g.Expect(probes[0].Succeeded).To(BeFalse())
+	g.Expect(probes[0].Message).To(ContainSubstring("The probe was not denied by the webhook"))
+	g.Expect(probes[1].Succeeded).To(BeFalse())
+	g.Expect(probes[1].Message).To(ContainSubstring("failed calling webhook"))
+	g.Expect(probes[2].Succeeded).To(BeFalse())
+	g.Expect(probes[2].Message).To(ContainSubstring("failed calling webhook"))
+
+	// test the mutating webhook probe fails if its canary is denied
+	mutated = true
+	mutationErr = denial(ValidationGatekeeperWebhook, "namespaces must have an owner")
+	probes = r.probeWebhooks(ctx, gatekeeper, now.Add(4*defaultProbeInterval))
+	g.Expect(probes).To(HaveLen(3))
+	g.Expect(probes[2].Webhook).To(Equal(MutationGatekeeperWebhook))
+	g.Expect(probes[2].Succeeded).To(BeFalse())
+	g.Expect(probes[2].Message).To(ContainSubstring("The probe was denied"))
+
+	// test mutating webhook disabled
+	disabled := operatorv1alpha1.WebhookDisabled
+	gatekeeper.Spec.MutatingWebhook = &disabled
+	probes = r.probeWebhooks(ctx, gatekeeper, now.Add(5*defaultProbeInterval))
+	g.Expect(probes).To(HaveLen(2))
+	g.Expect(probes[0].Webhook).To(Equal(CheckIgnoreLabelGatekeeperWebhook))
+	g.Expect(probes[1].Webhook).To(Equal(ValidationGatekeeperWebhook))
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(mutator), mutator))).To(BeTrue())
+
+	// test prober disabled
+	gatekeeper.Spec.Webhook = nil
+	g.Expect(r.probeWebhooks(ctx, gatekeeper, now.Add(6*defaultProbeInterval))).To(BeNil())
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(template), template))).To(BeTrue())
+	g.Expect(apierrors.IsNotFound(c.Get(ctx, client.ObjectKeyFromObject(constraint), constraint))).To(BeTrue())
+	gets := probeObjectGets
+	g.Expect(r.probeWebhooks(ctx, gatekeeper, now.Add(7*defaultProbeInterval))).To(BeNil())
+	g.Expect(probeObjectGets).To(Equal(gets))
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	EnablePprofArg = "--enable-pprof"
+	PprofPortArg   = "--pprof-port"
+
+	// CaptureProfileAnnotation requests the capture of a profile from a
+	// Gatekeeper pod. Its value is the name of the pod, optionally followed
+	// by a slash and the profile name e.g. "<pod>/heap". The CPU profile is
+	// captured by default.
+	CaptureProfileAnnotation = "operator.gatekeeper.sh/capture-profile"
+	// ProfileLabel is set on the Secrets containing captured profiles.
+	ProfileLabel                = "operator.gatekeeper.sh/profile"
+	ProfileCapturedAtAnnotation = "operator.gatekeeper.sh/captured-at"
+
+	defaultPprofPort      = 6060
+	defaultProfile        = "profile"
+	cpuProfileDuration    = 10 * time.Second
+	profileCaptureTimeout = time.Minute
+	// Secrets are limited to 1MiB.
+	maxProfileSize = 1024 * 1024
+	// profileAsset identifies the profile Secrets in the asset operation
+	// metrics.
+	profileAsset = "profile-secret"
+)
+
+var supportedProfiles = []string{"allocs", "block", "goroutine", "heap", "mutex", "profile", "threadcreate"}
+
+// ProfileFetcher fetches a pprof profile from a pod.
+type ProfileFetcher interface {
+	FetchProfile(ctx context.Context, namespace, pod string, port int32, profile string) ([]byte, error)
+}
+
+func pprofEnabled(profiling *operatorv1alpha1.ProfilingConfig) bool {
+	return profiling != nil &&
+		profiling.Pprof != nil &&
+		*profiling.Pprof == operatorv1alpha1.ProfilingEnabled
+}
+
+func pprofPort(profiling *operatorv1alpha1.ProfilingConfig) int32 {
+	if profiling != nil && profiling.Port != nil {
+		return *profiling.Port
+	}
+	return defaultPprofPort
+}
+
+func setProfiling(obj *unstructured.Unstructured, spec operatorv1alpha1.GatekeeperSpec) error {
+	if !pprofEnabled(spec.Profiling) {
+		return nil
+	}
+	if err := setContainerArg(obj, managerContainer, EnablePprofArg, "true", false); err != nil {
+		return err
+	}
+	return setContainerArg(obj, managerContainer, PprofPortArg, strconv.Itoa(int(pprofPort(spec.Profiling))), false)
+}
+
+// parseCaptureProfileAnnotation returns the pod and profile requested by the
+// given capture profile annotation value.
+func parseCaptureProfileAnnotation(value string) (pod, profile string, err error) {
+	pod, profile, _ = strings.Cut(strings.TrimSpace(value), "/")
+	if pod == "" {
+		return "", "", fmt.Errorf("annotation %s must be set to <pod>[/<profile>]", CaptureProfileAnnotation)
+	}
+	if profile == "" {
+		profile = defaultProfile
+	}
+	for _, p := range supportedProfiles {
+		if p == profile {
+			return pod, profile, nil
+		}
+	}
+	return "", "", fmt.Errorf("unsupported profile %s, must be one of %s", profile, strings.Join(supportedProfiles, ", "))
+}
+
+func profileSecretName(pod, profile string) string {
+	return fmt.Sprintf("gatekeeper-profile-%s-%s", profile, pod)
+}
+
+// captureProfile handles the profile capture requested by the capture profile
+// annotation of the Gatekeeper resource. The request is validated and the
+// annotation removed synchronously, while the profile, which takes
+// cpuProfileDuration to capture for the CPU profile, is fetched and stored in
+// a Secret in the background so that the reconciliation, e.g. setting the
+// webhook failure policy to Ignore, is not delayed. Only one profile is
+// captured at a time. Failed captures are reported as events and not retried.
+func (r *GatekeeperReconciler) captureProfile(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	value, ok := gatekeeper.GetAnnotations()[CaptureProfileAnnotation]
+	if !ok {
+		return nil
+	}
+
+	if err := r.startProfileCapture(ctx, gatekeeper, value); err != nil {
+		r.Log.Error(err, "Unable to capture profile", "annotation", value)
+		r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonProfileCaptureFailed, "Unable to capture profile %s: %v", value, err)
+	}
+
+	return r.removeAnnotation(ctx, gatekeeper, CaptureProfileAnnotation)
+}
+
+// startProfileCapture validates the capture profile request and starts
+// capturing the profile in the background.
+func (r *GatekeeperReconciler) startProfileCapture(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, value string) error {
+	if r.ProfileFetcher == nil {
+		return fmt.Errorf("profile capture is not configured")
+	}
+	if !pprofEnabled(gatekeeper.Spec.Profiling) {
+		return fmt.Errorf("pprof is not enabled in spec.profiling")
+	}
+	podName, profile, err := parseCaptureProfileAnnotation(value)
+	if err != nil {
+		return err
+	}
+
+	// Only allow capturing profiles from Gatekeeper pods.
+	namespace := r.gatekeeperNamespace()
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: podName}, pod); err != nil {
+		return errors.Wrapf(err, "Unable to get pod %s/%s", namespace, podName)
+	}
+	if pod.GetLabels()["gatekeeper.sh/system"] != "yes" {
+		return fmt.Errorf("pod %s/%s is not a Gatekeeper pod", namespace, podName)
+	}
+
+	r.profileCaptureMu.Lock()
+	defer r.profileCaptureMu.Unlock()
+	if r.profileCaptureDone != nil {
+		select {
+		case <-r.profileCaptureDone:
+		default:
+			return fmt.Errorf("another profile capture is in progress")
+		}
+	}
+	done := make(chan struct{})
+	r.profileCaptureDone = done
+
+	// The capture outlives the reconciliation, so it does not use its
+	// context and only uses copies of its state.
+	gatekeeper = gatekeeper.DeepCopy()
+	port := pprofPort(gatekeeper.Spec.Profiling)
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), profileCaptureTimeout)
+		defer cancel()
+		secretName, err := r.captureProfileToSecret(ctx, gatekeeper, namespace, podName, port, profile, value)
+		if err != nil {
+			r.Log.Error(err, "Unable to capture profile", "annotation", value)
+			r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonProfileCaptureFailed, "Unable to capture profile %s: %v", value, err)
+			return
+		}
+		r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonProfileCaptured, "Captured profile %s into Secret %s/%s", value, namespace, secretName)
+	}()
+	return nil
+}
+
+func (r *GatekeeperReconciler) captureProfileToSecret(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, namespace, podName string, port int32, profile, value string) (string, error) {
+	data, err := r.ProfileFetcher.FetchProfile(ctx, namespace, podName, port, profile)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxProfileSize {
+		return "", fmt.Errorf("profile of %d bytes exceeds the maximum Secret size", len(data))
+	}
+
+	name := profileSecretName(podName, profile)
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					ProfileLabel: profile,
+				},
+				"annotations": map[string]interface{}{
+					CaptureProfileAnnotation:    value,
+					ProfileCapturedAtAnnotation: metav1.Now().UTC().Format(time.RFC3339),
+				},
+			},
+			"type": string(corev1.SecretTypeOpaque),
+		},
+	}
+	if err := unstructured.SetNestedField(secret.Object, map[string]interface{}{
+		profile + ".pb.gz": base64.StdEncoding.EncodeToString(data),
+	}, "data"); err != nil {
+		return "", errors.Wrapf(err, "Unable to set profile data")
+	}
+	if err := r.crudResource(ctx, profileAsset, secret, gatekeeper, apply); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// moveProfiles moves the captured profile Secrets from the given namespace
+// Gatekeeper was migrated from to the current Gatekeeper namespace.
+func (r *GatekeeperReconciler) moveProfiles(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, source string) error {
+	secrets := &unstructured.UnstructuredList{}
+	secrets.SetAPIVersion("v1")
+	secrets.SetKind("SecretList")
+	if err := r.List(ctx, secrets, client.InNamespace(source), client.HasLabels{ProfileLabel}); err != nil {
+		return errors.Wrapf(err, "Unable to list the profile Secrets in namespace %s", source)
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		moved := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata": map[string]interface{}{
+					"name":      secret.GetName(),
+					"namespace": r.gatekeeperNamespace(),
+				},
+				"type": secret.Object["type"],
+				"data": secret.Object["data"],
+			},
+		}
+		moved.SetLabels(map[string]string{ProfileLabel: secret.GetLabels()[ProfileLabel]})
+		moved.SetAnnotations(secret.GetAnnotations())
+		if err := r.crudResource(ctx, profileAsset, moved, gatekeeper, apply); err != nil {
+			return err
+		}
+		if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "Unable to delete profile Secret %s/%s", source, secret.GetName())
+		}
+	}
+	return nil
+}
+
+// NewPortForwardProfileFetcher returns a ProfileFetcher fetching profiles
+// through a port-forward to the pod, since Gatekeeper only serves the pprof
+// endpoint on localhost.
+func NewPortForwardProfileFetcher(config *rest.Config) (ProfileFetcher, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create clientset")
+	}
+	return &portForwardProfileFetcher{config: config, clientset: clientset}, nil
+}
+
+type portForwardProfileFetcher struct {
+	config    *rest.Config
+	clientset kubernetes.Interface
+}
+
+func (f *portForwardProfileFetcher) FetchProfile(ctx context.Context, namespace, pod string, port int32, profile string) ([]byte, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(f.config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create port-forward round tripper")
+	}
+	url := f.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	defer close(stopCh)
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to port-forward to pod %s/%s", namespace, pod)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- forwarder.ForwardPorts()
+	}()
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return nil, errors.Wrapf(err, "Unable to port-forward to pod %s/%s", namespace, pod)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to get forwarded port for pod %s/%s", namespace, pod)
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no port forwarded for pod %s/%s", namespace, pod)
+	}
+
+	profileURL := fmt.Sprintf("http://localhost:%d/debug/pprof/%s", ports[0].Local, profile)
+	if profile == defaultProfile {
+		profileURL = fmt.Sprintf("%s?seconds=%d", profileURL, int(cpuProfileDuration.Seconds()))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, profileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to fetch profile %s from pod %s/%s", profile, namespace, pod)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch profile %s from pod %s/%s: %s", profile, namespace, pod, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxProfileSize+1))
+}
@@ -0,0 +1,185 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+func TestProfiling(t *testing.T) {
+	g := NewWithT(t)
+	enabled := operatorv1alpha1.ProfilingEnabled
+	port := int32(6061)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	for _, asset := range []string{AuditFile, WebhookFile} {
+		// test default
+		obj, err := util.GetManifestObject(asset)
+		g.Expect(err).ToNot(HaveOccurred())
+		err = crOverrides(gatekeeper, asset, obj, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		expectObjContainerArgument(g, managerContainer, obj).NotTo(HaveKey(EnablePprofArg))
+		expectObjContainerArgument(g, managerContainer, obj).NotTo(HaveKey(PprofPortArg))
+
+		// test enabled
+		gatekeeper.Spec.Profiling = &operatorv1alpha1.ProfilingConfig{
+			Pprof: &enabled,
+			Port:  &port,
+		}
+		err = crOverrides(gatekeeper, asset, obj, namespace, false, false)
+		g.Expect(err).ToNot(HaveOccurred())
+		expectObjContainerArgument(g, managerContainer, obj).To(HaveKeyWithValue(EnablePprofArg, "true"))
+		expectObjContainerArgument(g, managerContainer, obj).To(HaveKeyWithValue(PprofPortArg, "6061"))
+		gatekeeper.Spec.Profiling = nil
+	}
+}
+
+func TestParseCaptureProfileAnnotation(t *testing.T) {
+	g := NewWithT(t)
+
+	pod, profile, err := parseCaptureProfileAnnotation("gatekeeper-controller-manager-abc")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pod).To(Equal("gatekeeper-controller-manager-abc"))
+	g.Expect(profile).To(Equal(defaultProfile))
+
+	pod, profile, err = parseCaptureProfileAnnotation("gatekeeper-audit-abc/heap")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pod).To(Equal("gatekeeper-audit-abc"))
+	g.Expect(profile).To(Equal("heap"))
+
+	_, _, err = parseCaptureProfileAnnotation("gatekeeper-audit-abc/trace")
+	g.Expect(err).To(HaveOccurred())
+
+	_, _, err = parseCaptureProfileAnnotation("/heap")
+	g.Expect(err).To(HaveOccurred())
+}
+
+type fakeProfileFetcher struct {
+	pod     string
+	port    int32
+	profile string
+	// release blocks the fetch until closed, if set.
+	release chan struct{}
+}
+
+func (f *fakeProfileFetcher) FetchProfile(_ context.Context, _, pod string, port int32, profile string) ([]byte, error) {
+	if f.release != nil {
+		<-f.release
+	}
+	f.pod, f.port, f.profile = pod, port, profile
+	return []byte("profile data"), nil
+}
+
+func TestCaptureProfile(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+	enabled := operatorv1alpha1.ProfilingEnabled
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+			Annotations: map[string]string{
+				CaptureProfileAnnotation: "gatekeeper-audit-abc/heap",
+			},
+		},
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Profiling: &operatorv1alpha1.ProfilingConfig{
+				Pprof: &enabled,
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gatekeeper-audit-abc",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"gatekeeper.sh/system": "yes",
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gatekeeper, pod).Build()
+	fetcher := &fakeProfileFetcher{}
+	r := &GatekeeperReconciler{
+		Client:         c,
+		Log:            ctrl.Log,
+		Scheme:         scheme,
+		Namespace:      namespace,
+		ProfileFetcher: fetcher,
+	}
+
+	// test the capture completes in the background
+	fetcher.release = make(chan struct{})
+	g.Expect(r.captureProfile(ctx, gatekeeper)).To(Succeed())
+	clusterGatekeeper := &operatorv1alpha1.Gatekeeper{}
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(gatekeeper), clusterGatekeeper)).To(Succeed())
+	g.Expect(clusterGatekeeper.GetAnnotations()).NotTo(HaveKey(CaptureProfileAnnotation))
+
+	// test a concurrent capture is rejected
+	recorder := record.NewFakeRecorder(10)
+	r.Recorder = recorder
+	clusterGatekeeper.SetAnnotations(map[string]string{
+		CaptureProfileAnnotation: "gatekeeper-audit-abc",
+	})
+	g.Expect(r.captureProfile(ctx, clusterGatekeeper)).To(Succeed())
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("another profile capture is in progress")))
+	g.Expect(clusterGatekeeper.GetAnnotations()).NotTo(HaveKey(CaptureProfileAnnotation))
+
+	close(fetcher.release)
+	<-r.profileCaptureDone
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal Created Created Secret")))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal ProfileCaptured Captured profile gatekeeper-audit-abc/heap")))
+	g.Expect(fetcher.pod).To(Equal("gatekeeper-audit-abc"))
+	g.Expect(fetcher.port).To(Equal(int32(defaultPprofPort)))
+	g.Expect(fetcher.profile).To(Equal("heap"))
+
+	secret := &corev1.Secret{}
+	secretName := types.NamespacedName{Namespace: namespace, Name: profileSecretName("gatekeeper-audit-abc", "heap")}
+	g.Expect(c.Get(ctx, secretName, secret)).To(Succeed())
+	g.Expect(secret.Data).To(HaveKeyWithValue("heap.pb.gz", []byte("profile data")))
+	g.Expect(secret.GetLabels()).To(HaveKeyWithValue(ProfileLabel, "heap"))
+
+	// test non Gatekeeper pod
+	pod.SetName("other")
+	pod.SetLabels(nil)
+	pod.SetResourceVersion("")
+	g.Expect(c.Create(ctx, pod)).To(Succeed())
+	clusterGatekeeper.SetAnnotations(map[string]string{
+		CaptureProfileAnnotation: "other",
+	})
+	fetcher.pod = ""
+	g.Expect(r.captureProfile(ctx, clusterGatekeeper)).To(Succeed())
+	g.Expect(fetcher.pod).To(BeEmpty())
+	g.Expect(clusterGatekeeper.GetAnnotations()).NotTo(HaveKey(CaptureProfileAnnotation))
+}
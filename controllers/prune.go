@@ -0,0 +1,285 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
+)
+
+const (
+	// InventoryLabel labels the resources applied by the operator with the
+	// UID of the Gatekeeper resource they belong to.
+	InventoryLabel = "operator.gatekeeper.sh/inventory"
+	// ManifestVersionLabel labels the resources applied by the operator with
+	// the Gatekeeper version of the manifests they were applied from.
+	ManifestVersionLabel   = "operator.gatekeeper.sh/manifest-version"
+	unknownManifestVersion = "unknown"
+)
+
+var podSecurityPolicyGVK = schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}
+
+// legacyInventoryKinds are the kinds of resources that are no longer part of
+// any Gatekeeper manifest but may still be labeled with an inventory.
+var legacyInventoryKinds = []schema.GroupVersionKind{podSecurityPolicyGVK}
+
+// unlabeledLegacyKinds are the kinds of the resources that earlier Gatekeeper
+// manifests shipped and that operator releases predating pruning applied
+// without inventory labels, i.e. the PodSecurityPolicy and the Roles that
+// were since removed. They are found by the Gatekeeper system label instead,
+// in the Gatekeeper namespace for the Roles.
+var unlabeledLegacyKinds = []schema.GroupVersionKind{
+	podSecurityPolicyGVK,
+	{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+}
+
+var (
+	manifestVersionOnce  sync.Once
+	manifestVersionValue string
+)
+
+// manifestVersion returns the Gatekeeper version of the manifests, i.e. the
+// tag of the image in the webhook deployment manifest.
+func manifestVersion() string {
+	manifestVersionOnce.Do(func() {
+		manifestVersionValue = unknownManifestVersion
+		obj, err := util.GetManifestObject(WebhookFile)
+		if err != nil {
+			return
+		}
+		containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok || container["name"] != managerContainer {
+				continue
+			}
+			image, _ := container["image"].(string)
+			if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+				manifestVersionValue = image[i+1:]
+			}
+		}
+	})
+	return manifestVersionValue
+}
+
+func pruningMode(gatekeeper *operatorv1alpha1.Gatekeeper) operatorv1alpha1.PruningMode {
+	if gatekeeper.Spec.Pruning != nil {
+		return *gatekeeper.Spec.Pruning
+	}
+	return operatorv1alpha1.PruningDryRun
+}
+
+// setInventoryLabels labels the given object with the inventory of the
+// Gatekeeper resource and the manifest version.
+func setInventoryLabels(obj *unstructured.Unstructured, gatekeeper *operatorv1alpha1.Gatekeeper) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[InventoryLabel] = string(gatekeeper.GetUID())
+	labels[ManifestVersionLabel] = manifestVersion()
+	obj.SetLabels(labels)
+}
+
+func inventoryKey(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Kind, obj.GetNamespace(), obj.GetName())
+}
+
+// prunedAsset identifies the pruned resources of the given kind in the asset
+// operation metrics.
+func prunedAsset(gvk schema.GroupVersionKind) string {
+	return "pruned-" + strings.ToLower(gvk.Kind)
+}
+
+// inventoryObjects returns all the resources the operator may apply to the
+// Gatekeeper namespace, whether they are enabled by the Gatekeeper resource
+// or not. The resources that are disabled are deleted by the reconciliation
+// itself.
+func (r *GatekeeperReconciler) inventoryObjects() ([]*unstructured.Unstructured, error) {
+	applyMonitoringAssets, deleteMonitoringAssets := getMonitoringAssets(&operatorv1alpha1.Gatekeeper{}, true, true)
+	assets := append([]string{}, orderedStaticAssets...)
+	assets = append(assets, webhookStaticAssets...)
+	assets = append(assets, applyMonitoringAssets...)
+	assets = append(assets, deleteMonitoringAssets...)
+
+	var objs []*unstructured.Unstructured
+	for _, asset := range assets {
+		obj, err := util.GetManifestObject(asset)
+		if err != nil {
+			return nil, err
+		}
+		if err := setNamespace(obj, asset, r.gatekeeperNamespace()); err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	objs = append(objs, webhookLoadBalancerService(r.gatekeeperNamespace(), defaultWebhookURLPort, ""), webhookRoute(r.gatekeeperNamespace(), ""))
+	if r.OperatorNamespace != "" {
+		objs = append(objs, operatorServiceMonitor(r.OperatorNamespace))
+	}
+	return objs, nil
+}
+
+// pruneObsoleteResources deletes the resources labeled with the inventory of
+// the Gatekeeper resource that are no longer part of the Gatekeeper
+// manifests, e.g. because a newer Gatekeeper release dropped or renamed them,
+// along with the unlabeled legacy resources. With the DryRun pruning mode,
+// they are returned instead. Namespaces are never pruned since that would
+// delete everything within them, and obsolete CRDs are always returned rather
+// than deleted since that would delete their custom resources.
+//
+// Resources only become obsolete when the manifests change, so the scan,
+// which lists every inventory kind across the cluster, is skipped and the
+// previous candidates returned until the manifest version, the Gatekeeper
+// namespace or the pruning mode change.
+func (r *GatekeeperReconciler) pruneObsoleteResources(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper) ([]string, error) {
+	mode := pruningMode(gatekeeper)
+	if mode == operatorv1alpha1.PruningDisabled {
+		r.pruneScan = ""
+		return nil, nil
+	}
+	scan := strings.Join([]string{string(gatekeeper.GetUID()), manifestVersion(), r.gatekeeperNamespace(), string(mode)}, "/")
+	if scan == r.pruneScan {
+		return gatekeeper.Status.PruneCandidates, nil
+	}
+
+	objs, err := r.inventoryObjects()
+	if err != nil {
+		return nil, err
+	}
+	desired := map[string]bool{}
+	kinds := append([]schema.GroupVersionKind{}, legacyInventoryKinds...)
+	for _, obj := range objs {
+		desired[inventoryKey(obj)] = true
+		gvk := obj.GroupVersionKind()
+		known := gvk.Kind == util.NamespaceKind
+		for _, kind := range kinds {
+			known = known || kind == gvk
+		}
+		if !known {
+			kinds = append(kinds, gvk)
+		}
+	}
+
+	var candidates []string
+	obsoleteCRDs := false
+	for _, gvk := range kinds {
+		items, err := r.listPruneCandidates(ctx, gvk, gatekeeper)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			obj := &items[i]
+			if desired[inventoryKey(obj)] {
+				continue
+			}
+			candidate := obj.GetKind() + " " + obj.GetName()
+			if obj.GetNamespace() != "" {
+				candidate = fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+			}
+			if gvk.Kind == "CustomResourceDefinition" {
+				obsoleteCRDs = true
+				candidates = append(candidates, candidate)
+				continue
+			}
+			if mode == operatorv1alpha1.PruningDryRun {
+				candidates = append(candidates, candidate)
+				continue
+			}
+			manifestVersion := obj.GetLabels()[ManifestVersionLabel]
+			if manifestVersion == "" {
+				manifestVersion = unknownManifestVersion
+			}
+			r.Log.Info("Pruning obsolete Gatekeeper resource", "resource", candidate, "manifestVersion", manifestVersion)
+			if err := r.crudResource(ctx, prunedAsset(gvk), obj, gatekeeper, delete); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(candidates) > 0 && !reflect.DeepEqual(candidates, gatekeeper.Status.PruneCandidates) {
+		r.Log.Info("Found obsolete Gatekeeper resources to prune", "resources", candidates)
+		hint := "set spec.pruning to Enabled to delete them"
+		switch {
+		case mode == operatorv1alpha1.PruningEnabled:
+			hint = "CustomResourceDefinitions must be deleted manually once their custom resources are no longer needed"
+		case obsoleteCRDs:
+			hint += ", except the CustomResourceDefinitions which must be deleted manually"
+		}
+		r.recordEvent(gatekeeper, corev1.EventTypeNormal, EventReasonPruneCandidates,
+			"Found %d obsolete resources to prune, %s: %s", len(candidates), hint, strings.Join(candidates, ", "))
+	}
+	r.pruneScan = scan
+	return candidates, nil
+}
+
+// listPruneCandidates returns the resources of the given kind labeled with
+// the inventory of the Gatekeeper resource and, for the unlabeled legacy
+// kinds, the resources of the Gatekeeper manifests without inventory labels.
+func (r *GatekeeperReconciler) listPruneCandidates(ctx context.Context, gvk schema.GroupVersionKind, gatekeeper *operatorv1alpha1.Gatekeeper) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, list, client.MatchingLabels{InventoryLabel: string(gatekeeper.GetUID())}); err != nil {
+		// The API of the kind is not served, e.g. routes outside of OpenShift
+		// or PodSecurityPolicies since Kubernetes 1.25.
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "Unable to list %s", gvk.Kind)
+	}
+	items := list.Items
+
+	for _, kind := range unlabeledLegacyKinds {
+		if kind != gvk {
+			continue
+		}
+		legacy := &unstructured.UnstructuredList{}
+		legacy.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		namespace := ""
+		if gvk.Kind == "Role" {
+			namespace = r.gatekeeperNamespace()
+		}
+		if err := r.List(ctx, legacy, client.InNamespace(namespace), client.MatchingLabels{SystemLabel: "yes"}); err != nil {
+			return nil, errors.Wrapf(err, "Unable to list %s", gvk.Kind)
+		}
+		for _, item := range legacy.Items {
+			if _, ok := item.GetLabels()[InventoryLabel]; !ok {
+				items = append(items, item)
+			}
+		}
+	}
+
+	for i := range items {
+		items[i].SetGroupVersionKind(gvk)
+	}
+	return items, nil
+}
@@ -0,0 +1,162 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+func TestManifestVersion(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(manifestVersion()).To(HavePrefix("v"))
+}
+
+func TestSetInventoryLabels(t *testing.T) {
+	g := NewWithT(t)
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+			UID:  types.UID("gatekeeper-uid"),
+		},
+	}
+	obj := inventoryObject("v1", "Service", namespace, WebhookServiceName, "")
+	obj.SetLabels(map[string]string{"gatekeeper.sh/system": "yes"})
+	setInventoryLabels(obj, gatekeeper)
+	g.Expect(obj.GetLabels()).To(HaveKeyWithValue("gatekeeper.sh/system", "yes"))
+	g.Expect(obj.GetLabels()).To(HaveKeyWithValue(InventoryLabel, "gatekeeper-uid"))
+	g.Expect(obj.GetLabels()).To(HaveKeyWithValue(ManifestVersionLabel, manifestVersion()))
+}
+
+func TestPruneObsoleteResources(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(apiextensionsv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+			UID:  types.UID("gatekeeper-uid"),
+		},
+	}
+	current := inventoryObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "gatekeeper-manager-role", "gatekeeper-uid")
+	psp := inventoryObject("policy/v1beta1", "PodSecurityPolicy", "", "gatekeeper-admin", "gatekeeper-uid")
+	renamed := inventoryObject("rbac.authorization.k8s.io/v1", "Role", namespace, "gatekeeper-old-role", "gatekeeper-uid")
+	unlabeled := inventoryObject("rbac.authorization.k8s.io/v1", "Role", namespace, "other-role", "")
+	otherInventory := inventoryObject("rbac.authorization.k8s.io/v1", "Role", namespace, "other-inventory-role", "other-uid")
+	oldNamespace := inventoryObject("v1", "Namespace", "", "old-gatekeeper-system", "gatekeeper-uid")
+	oldCRD := inventoryObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "mutators.mutations.gatekeeper.sh", "gatekeeper-uid")
+	legacyRole := inventoryObject("rbac.authorization.k8s.io/v1", "Role", namespace, "gatekeeper-legacy-role", "")
+	legacyRole.SetLabels(map[string]string{SystemLabel: "yes"})
+	otherNamespaceRole := inventoryObject("rbac.authorization.k8s.io/v1", "Role", "default", "gatekeeper-legacy-role", "")
+	otherNamespaceRole.SetLabels(map[string]string{SystemLabel: "yes"})
+	objs := []client.Object{current, psp, renamed, unlabeled, otherInventory, oldNamespace, oldCRD, legacyRole, otherNamespaceRole}
+	lists := 0
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			lists++
+			return c.List(ctx, list, opts...)
+		},
+	}).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace, Recorder: recorder}
+
+	// test disabled
+	disabled := operatorv1alpha1.PruningDisabled
+	gatekeeper.Spec.Pruning = &disabled
+	g.Expect(r.pruneObsoleteResources(ctx, gatekeeper)).To(BeEmpty())
+
+	// test dry run by default only reports the candidates, including the
+	// unlabeled legacy resources
+	gatekeeper.Spec.Pruning = nil
+	candidates, err := r.pruneObsoleteResources(ctx, gatekeeper)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(candidates).To(ConsistOf(
+		"PodSecurityPolicy gatekeeper-admin",
+		"Role "+namespace+"/gatekeeper-old-role",
+		"Role "+namespace+"/gatekeeper-legacy-role",
+		"CustomResourceDefinition mutators.mutations.gatekeeper.sh",
+	))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal PruneCandidates Found 4 obsolete resources to prune, set spec.pruning to Enabled to delete them, except the CustomResourceDefinitions")))
+	for _, obj := range objs {
+		g.Expect(c.Get(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+	}
+
+	// test the scan is skipped until the manifest version, namespace or mode
+	// change
+	gatekeeper.Status.PruneCandidates = candidates
+	lists = 0
+	g.Expect(r.pruneObsoleteResources(ctx, gatekeeper)).To(Equal(candidates))
+	g.Expect(lists).To(BeZero())
+	g.Expect(recorder.Events).To(BeEmpty())
+	dryRun := operatorv1alpha1.PruningDryRun
+	gatekeeper.Spec.Pruning = &dryRun
+	g.Expect(r.pruneObsoleteResources(ctx, gatekeeper)).To(Equal(candidates))
+	g.Expect(lists).To(BeZero())
+	r.pruneScan = ""
+	g.Expect(r.pruneObsoleteResources(ctx, gatekeeper)).To(ConsistOf(candidates))
+	g.Expect(lists).ToNot(BeZero())
+
+	// test enabled prunes, except the CRDs
+	enabled := operatorv1alpha1.PruningEnabled
+	gatekeeper.Spec.Pruning = &enabled
+	g.Expect(r.pruneObsoleteResources(ctx, gatekeeper)).To(Equal([]string{"CustomResourceDefinition mutators.mutations.gatekeeper.sh"}))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal Deleted Deleted PodSecurityPolicy")))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal Deleted Deleted Role")))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal Deleted Deleted Role")))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal PruneCandidates Found 1 obsolete resources to prune, CustomResourceDefinitions must be deleted manually")))
+	for _, obj := range objs {
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if obj == psp || obj == renamed || obj == legacyRole {
+			g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), obj.GetName())
+		} else {
+			g.Expect(err).ToNot(HaveOccurred(), obj.GetName())
+		}
+	}
+}
+
+func inventoryObject(apiVersion, kind, namespace, name, inventory string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	if inventory != "" {
+		obj.SetLabels(map[string]string{
+			InventoryLabel:       inventory,
+			ManifestVersionLabel: "v3.10.0",
+		})
+	}
+	return obj
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	ConditionReasonSLOBreached = "SLOBreached"
+
+	defaultWebhookMetricsPort = 8888
+	defaultSLOInterval        = time.Minute
+	sloScrapeTimeout          = 10 * time.Second
+	sloLatencyQuantile        = 0.99
+	admissionStatusLabel      = "admission_status"
+	mutationStatusLabel       = "mutation_status"
+	admissionStatusError      = "error"
+)
+
+// webhookRequestDurationMetrics are the Gatekeeper histograms of the
+// admission request latency, with the label holding the request status.
+var webhookRequestDurationMetrics = map[string]string{
+	"gatekeeper_validation_request_duration_seconds": admissionStatusLabel,
+	"gatekeeper_mutation_request_duration_seconds":   mutationStatusLabel,
+}
+
+var sloScrapeClient = &http.Client{Timeout: sloScrapeTimeout}
+
+// webhookMetricsSample is the cumulative admission request latency histogram
+// of a webhook pod, summed over the validation and mutation requests.
+type webhookMetricsSample struct {
+	// buckets maps the upper bound of each bucket to its cumulative count.
+	buckets map[float64]float64
+	count   float64
+	errors  float64
+}
+
+// sub returns the requests of the sample since the given previous sample of
+// the same pod. The whole sample is returned if the pod restarted.
+func (s webhookMetricsSample) sub(prev webhookMetricsSample) webhookMetricsSample {
+	if prev.count > s.count || prev.errors > s.errors {
+		return s
+	}
+	delta := webhookMetricsSample{
+		buckets: map[float64]float64{},
+		count:   s.count - prev.count,
+		errors:  s.errors - prev.errors,
+	}
+	for le, count := range s.buckets {
+		delta.buckets[le] = count - prev.buckets[le]
+	}
+	return delta
+}
+
+// add adds the requests of the given sample to the sample.
+func (s *webhookMetricsSample) add(other webhookMetricsSample) {
+	if s.buckets == nil {
+		s.buckets = map[float64]float64{}
+	}
+	for le, count := range other.buckets {
+		s.buckets[le] += count
+	}
+	s.count += other.count
+	s.errors += other.errors
+}
+
+// quantile estimates the given quantile of the request latency in seconds
+// from the histogram buckets, interpolating linearly within the bucket like
+// the Prometheus histogram_quantile function.
+func (s webhookMetricsSample) quantile(q float64) float64 {
+	bounds := make([]float64, 0, len(s.buckets))
+	for le := range s.buckets {
+		bounds = append(bounds, le)
+	}
+	sort.Float64s(bounds)
+	if len(bounds) == 0 || s.buckets[bounds[len(bounds)-1]] == 0 {
+		return 0
+	}
+
+	rank := q * s.buckets[bounds[len(bounds)-1]]
+	lowerBound, lowerCount := 0.0, 0.0
+	for _, le := range bounds {
+		count := s.buckets[le]
+		if count >= rank {
+			if math.IsInf(le, 1) {
+				return lowerBound
+			}
+			if count == lowerCount {
+				return le
+			}
+			return lowerBound + (le-lowerBound)*(rank-lowerCount)/(count-lowerCount)
+		}
+		lowerBound, lowerCount = le, count
+	}
+	return lowerBound
+}
+
+// parseWebhookMetrics parses the admission request latency histograms from
+// the given Prometheus text exposition of the metrics of a webhook pod.
+func parseWebhookMetrics(r io.Reader) (webhookMetricsSample, error) {
+	sample := webhookMetricsSample{buckets: map[float64]float64{}}
+	parser := expfmt.TextParser{}
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return sample, errors.Wrap(err, "Unable to parse metrics")
+	}
+	for name, statusLabel := range webhookRequestDurationMetrics {
+		family, ok := families[name]
+		if !ok || family.GetType() != dto.MetricType_HISTOGRAM {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			histogram := metric.GetHistogram()
+			count := float64(histogram.GetSampleCount())
+			sample.count += count
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == statusLabel && label.GetValue() == admissionStatusError {
+					sample.errors += count
+				}
+			}
+			for _, bucket := range histogram.GetBucket() {
+				if math.IsInf(bucket.GetUpperBound(), 1) {
+					continue
+				}
+				sample.buckets[bucket.GetUpperBound()] += float64(bucket.GetCumulativeCount())
+			}
+			sample.buckets[math.Inf(1)] += count
+		}
+	}
+	return sample, nil
+}
+
+func webhookMetricsPort(spec operatorv1alpha1.GatekeeperSpec) int32 {
+	if spec.Webhook != nil && spec.Webhook.MetricsPort != nil {
+		return *spec.Webhook.MetricsPort
+	}
+	if spec.Metrics != nil && spec.Metrics.PrometheusPort != nil {
+		return *spec.Metrics.PrometheusPort
+	}
+	return defaultWebhookMetricsPort
+}
+
+// webhookSLOInterval returns the interval between the evaluations of the
+// webhook SLO, or zero if no SLO is configured.
+func webhookSLOInterval(gatekeeper *operatorv1alpha1.Gatekeeper) time.Duration {
+	if gatekeeper.Spec.Webhook == nil || gatekeeper.Spec.Webhook.SLO == nil {
+		return 0
+	}
+	if interval := gatekeeper.Spec.Webhook.SLO.Interval; interval != nil && interval.Duration > 0 {
+		return interval.Duration
+	}
+	return defaultSLOInterval
+}
+
+// evaluateWebhookSLO scrapes the metrics of the webhook pods if the SLO
+// interval elapsed since the previous evaluation and returns a description of
+// the breached objectives, or an empty string if the objectives are met. Only
+// the requests handled since the previous evaluation are taken into account.
+// The result of the previous evaluation is returned if the interval did not
+// elapse, if no request was handled since then or if the metrics could not be
+// scraped.
+func (r *GatekeeperReconciler) evaluateWebhookSLO(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, now time.Time) (string, error) {
+	interval := webhookSLOInterval(gatekeeper)
+	if interval == 0 {
+		r.webhookSLOSamples = nil
+		r.lastWebhookSLOEvaluation = time.Time{}
+		r.webhookSLOBreach = ""
+		return "", nil
+	}
+	if !r.lastWebhookSLOEvaluation.IsZero() && now.Sub(r.lastWebhookSLOEvaluation) < interval {
+		return r.webhookSLOBreach, nil
+	}
+	r.lastWebhookSLOEvaluation = now
+	slo := gatekeeper.Spec.Webhook.SLO
+
+	pods := &unstructured.UnstructuredList{}
+	pods.SetAPIVersion("v1")
+	pods.SetKind("PodList")
+	err := r.List(ctx, pods, client.InNamespace(r.gatekeeperNamespace()), client.MatchingLabels{
+		"control-plane":           "controller-manager",
+		"gatekeeper.sh/operation": "webhook",
+	})
+	if err != nil {
+		return r.webhookSLOBreach, errors.Wrapf(err, "Unable to list webhook pods in namespace %s", r.gatekeeperNamespace())
+	}
+
+	port := strconv.Itoa(int(webhookMetricsPort(gatekeeper.Spec)))
+	samples := map[string]webhookMetricsSample{}
+	total := webhookMetricsSample{}
+	for _, pod := range pods.Items {
+		podIP, _, _ := unstructured.NestedString(pod.Object, "status", "podIP")
+		if podIP == "" {
+			continue
+		}
+		sample, err := scrapeWebhookMetrics(ctx, fmt.Sprintf("http://%s/metrics", net.JoinHostPort(podIP, port)))
+		if err != nil {
+			return r.webhookSLOBreach, errors.Wrapf(err, "Unable to scrape metrics of pod %s/%s", r.gatekeeperNamespace(), pod.GetName())
+		}
+		samples[pod.GetName()] = sample
+		if prev, ok := r.webhookSLOSamples[pod.GetName()]; ok {
+			sample = sample.sub(prev)
+		}
+		total.add(sample)
+	}
+	r.webhookSLOSamples = samples
+
+	if total.count == 0 {
+		return r.webhookSLOBreach, nil
+	}
+	var breaches []string
+	if slo.LatencyP99Threshold != nil {
+		p99 := time.Duration(total.quantile(sloLatencyQuantile) * float64(time.Second))
+		if p99 > slo.LatencyP99Threshold.Duration {
+			breaches = append(breaches, fmt.Sprintf("p99 admission latency %s exceeds %s", p99, slo.LatencyP99Threshold.Duration))
+		}
+	}
+	if slo.ErrorRatioThreshold != nil {
+		threshold, err := strconv.ParseFloat(*slo.ErrorRatioThreshold, 64)
+		if err != nil {
+			return r.webhookSLOBreach, errors.Wrapf(err, "Invalid errorRatioThreshold %s", *slo.ErrorRatioThreshold)
+		}
+		if ratio := total.errors / total.count; ratio > threshold {
+			breaches = append(breaches, fmt.Sprintf("admission error ratio %.4f exceeds %s", ratio, *slo.ErrorRatioThreshold))
+		}
+	}
+	r.webhookSLOBreach = ""
+	if len(breaches) > 0 {
+		r.webhookSLOBreach = fmt.Sprintf("Webhook SLO breached over %.0f admission requests: %s", total.count, strings.Join(breaches, ", "))
+	}
+	return r.webhookSLOBreach, nil
+}
+
+func scrapeWebhookMetrics(ctx context.Context, url string) (webhookMetricsSample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return webhookMetricsSample{}, err
+	}
+	req.Header.Set("Accept", string(expfmt.FmtText))
+	resp, err := sloScrapeClient.Do(req)
+	if err != nil {
+		return webhookMetricsSample{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return webhookMetricsSample{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return parseWebhookMetrics(resp.Body)
+}
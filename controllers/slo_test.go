@@ -0,0 +1,193 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+// webhookMetrics returns the Prometheus text exposition of the validation
+// request latency histogram of a webhook pod with the given number of
+// allowed requests served in 10ms, slow requests served in 2s and errors.
+func webhookMetrics(allowed, slow, errored int) string {
+	var b strings.Builder
+	b.WriteString("# HELP gatekeeper_validation_request_duration_seconds The response time in seconds\n")
+	b.WriteString("# TYPE gatekeeper_validation_request_duration_seconds histogram\n")
+	for _, series := range []struct {
+		status    string
+		fast, all int
+	}{
+		{"allow", allowed, allowed + slow},
+		{"error", 0, errored},
+	} {
+		for _, le := range []string{"0.01", "0.1", "1", "3"} {
+			count := series.fast
+			if le == "3" {
+				count = series.all
+			}
+			fmt.Fprintf(&b, "gatekeeper_validation_request_duration_seconds_bucket{admission_status=%q,le=%q} %d\n", series.status, le, count)
+		}
+		fmt.Fprintf(&b, "gatekeeper_validation_request_duration_seconds_bucket{admission_status=%q,le=\"+Inf\"} %d\n", series.status, series.all)
+		fmt.Fprintf(&b, "gatekeeper_validation_request_duration_seconds_sum{admission_status=%q} 0\n", series.status)
+		fmt.Fprintf(&b, "gatekeeper_validation_request_duration_seconds_count{admission_status=%q} %d\n", series.status, series.all)
+	}
+	return b.String()
+}
+
+func TestParseWebhookMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	sample, err := parseWebhookMetrics(strings.NewReader(webhookMetrics(990, 10, 5)))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(sample.count).To(Equal(1005.0))
+	g.Expect(sample.errors).To(Equal(5.0))
+	g.Expect(sample.quantile(0.5)).To(BeNumerically("<=", 0.01))
+	g.Expect(sample.quantile(0.999)).To(BeNumerically(">", 1))
+
+	// test delta since the previous sample
+	prev, err := parseWebhookMetrics(strings.NewReader(webhookMetrics(100, 10, 5)))
+	g.Expect(err).ToNot(HaveOccurred())
+	delta := sample.sub(prev)
+	g.Expect(delta.count).To(Equal(890.0))
+	g.Expect(delta.errors).To(Equal(0.0))
+	g.Expect(delta.quantile(0.99)).To(BeNumerically("<=", 0.01))
+
+	// test restarted pod
+	g.Expect(prev.sub(sample).count).To(Equal(prev.count))
+
+	_, err = parseWebhookMetrics(strings.NewReader("invalid metrics"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestEvaluateWebhookSLO(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	metrics := webhookMetrics(1000, 0, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		g.Expect(req.URL.Path).To(Equal("/metrics"))
+		fmt.Fprint(w, metrics)
+	}))
+	defer server.Close()
+	host, portString, err := net.SplitHostPort(server.Listener.Addr().String())
+	g.Expect(err).ToNot(HaveOccurred())
+	port, err := strconv.Atoi(portString)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gatekeeper-controller-manager-abc",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"control-plane":           "controller-manager",
+				"gatekeeper.sh/operation": "webhook",
+			},
+		},
+		Status: corev1.PodStatus{
+			PodIP: host,
+		},
+	}
+	r := &GatekeeperReconciler{
+		Client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build(),
+		Log:       ctrl.Log,
+		Scheme:    scheme,
+		Namespace: namespace,
+	}
+	metricsPort := int32(port)
+	errorRatio := "0.05"
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Webhook: &operatorv1alpha1.WebhookConfig{
+				MetricsPort: &metricsPort,
+			},
+		},
+	}
+
+	// test no SLO
+	now := time.Now()
+	breach, err := r.evaluateWebhookSLO(ctx, gatekeeper, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(breach).To(BeEmpty())
+	g.Expect(webhookSLOInterval(gatekeeper)).To(BeZero())
+
+	// test met
+	gatekeeper.Spec.Webhook.SLO = &operatorv1alpha1.WebhookSLOConfig{
+		LatencyP99Threshold: &metav1.Duration{Duration: time.Second},
+		ErrorRatioThreshold: &errorRatio,
+	}
+	g.Expect(webhookSLOInterval(gatekeeper)).To(Equal(defaultSLOInterval))
+	breach, err = r.evaluateWebhookSLO(ctx, gatekeeper, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(breach).To(BeEmpty())
+
+	// test not evaluated before the interval elapsed
+	metrics = webhookMetrics(1000, 100, 100)
+	breach, err = r.evaluateWebhookSLO(ctx, gatekeeper, now.Add(defaultSLOInterval/2))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(breach).To(BeEmpty())
+
+	// test breached since the previous evaluation
+	now = now.Add(defaultSLOInterval)
+	breach, err = r.evaluateWebhookSLO(ctx, gatekeeper, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(breach).To(ContainSubstring("over 200 admission requests"))
+	g.Expect(breach).To(ContainSubstring("p99 admission latency"))
+	g.Expect(breach).To(ContainSubstring("admission error ratio 0.5000 exceeds 0.05"))
+	breached := breach
+
+	// test the breach is kept before the interval elapsed
+	breach, err = r.evaluateWebhookSLO(ctx, gatekeeper, now.Add(time.Second))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(breach).To(Equal(breached))
+
+	// test the breach is kept without requests since the previous evaluation
+	now = now.Add(defaultSLOInterval)
+	breach, err = r.evaluateWebhookSLO(ctx, gatekeeper, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(breach).To(Equal(breached))
+
+	// test the breach is kept if the metrics cannot be scraped
+	metrics = "invalid metrics"
+	now = now.Add(defaultSLOInterval)
+	breach, err = r.evaluateWebhookSLO(ctx, gatekeeper, now)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(breach).To(Equal(breached))
+
+	// test met again
+	metrics = webhookMetrics(2000, 100, 100)
+	now = now.Add(defaultSLOInterval)
+	breach, err = r.evaluateWebhookSLO(ctx, gatekeeper, now)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(breach).To(BeEmpty())
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	ConditionReasonDeploymentReady    = "DeploymentReady"
+	ConditionReasonDeploymentNotReady = "DeploymentNotReady"
+)
+
+// webhookState is the state of the webhook observed during a
+// reconciliation.
+type webhookState struct {
+	// failOpen is whether the failure policy of the webhooks is set to
+	// Ignore.
+	failOpen bool
+	// policyNotReady describes the policies that are not yet enforced on all
+	// webhook pods while the policy ready gate keeps failOpen set.
+	policyNotReady string
+	// policyTimedOut is whether the policy ready gate timed out.
+	policyTimedOut bool
+	// sloBreach describes the breached webhook SLO, if any.
+	sloBreach string
+	// webhookProbes are the results of the webhook probes, or nil if the
+	// webhooks were not probed.
+	webhookProbes []operatorv1alpha1.WebhookProbeStatus
+	// blockedMutators is the number of mutators blocking the deletion of the
+	// mutating CRDs.
+	blockedMutators int32
+	// pruneCandidates are the obsolete resources that would be pruned if
+	// pruning was enabled.
+	pruneCandidates []string
+	// namespace is the namespace Gatekeeper was last completely deployed to.
+	namespace string
+	// migrating is whether Gatekeeper is being migrated to the namespace set
+	// by spec.namespace.
+	migrating bool
+	// images are the images running in the Gatekeeper pods.
+	images []operatorv1alpha1.PodImageStatus
+}
+
+// updateWebhookStatus records whether the webhook is ready in the webhook
+// conditions of the Gatekeeper resource. The webhook is Degraded while its
+// deployment is not ready, since its failure policy is then set to Ignore and
+// admission requests are not enforced, while its SLO is breached or once the
+// policy ready gate timed out.
+func (r *GatekeeperReconciler) updateWebhookStatus(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, state webhookState) error {
+	patch := client.MergeFrom(gatekeeper.DeepCopy())
+
+	now := metav1.Now()
+	status := &gatekeeper.Status
+	status.ObservedGeneration = gatekeeper.GetGeneration()
+	if status.AuditConditions == nil {
+		status.AuditConditions = []operatorv1alpha1.StatusCondition{}
+	}
+	ready, degraded := corev1.ConditionTrue, corev1.ConditionFalse
+	reason := ConditionReasonDeploymentReady
+	message := fmt.Sprintf("Deployment %s is ready", WebhookDeploymentName)
+	switch {
+	case state.policyNotReady != "":
+		ready = corev1.ConditionFalse
+		if state.policyTimedOut {
+			degraded = corev1.ConditionTrue
+		}
+		reason = ConditionReasonPolicyNotReady
+		message = fmt.Sprintf("%s, the webhook failure policy is set to Ignore", state.policyNotReady)
+	case state.failOpen:
+		ready, degraded = corev1.ConditionFalse, corev1.ConditionTrue
+		reason = ConditionReasonDeploymentNotReady
+		message = fmt.Sprintf("Deployment %s is not ready, the webhook failure policy is set to Ignore", WebhookDeploymentName)
+	}
+	status.WebhookConditions = setStatusCondition(status.WebhookConditions, operatorv1alpha1.StatusReady, ready, reason, message, now)
+	if !state.failOpen && state.sloBreach != "" {
+		degraded, reason, message = corev1.ConditionTrue, ConditionReasonSLOBreached, state.sloBreach
+	}
+	status.WebhookConditions = setStatusCondition(status.WebhookConditions, operatorv1alpha1.StatusDegraded, degraded, reason, message, now)
+	switch {
+	case webhookProbeInterval(gatekeeper) == 0:
+		status.WebhookProbes = nil
+	case state.webhookProbes != nil:
+		status.WebhookProbes = state.webhookProbes
+	}
+	status.BlockedMutators = state.blockedMutators
+	status.PruneCandidates = state.pruneCandidates
+	status.Namespace = state.namespace
+	status.Images = state.images
+
+	if err := r.Status().Patch(ctx, gatekeeper, patch); err != nil {
+		return errors.Wrapf(err, "Unable to update the status of Gatekeeper %s", gatekeeper.GetName())
+	}
+	return nil
+}
+
+// setStatusCondition sets the condition of the given type in the given
+// conditions. The last transition time is only updated if the status of the
+// condition changes.
+func setStatusCondition(
+	conditions []operatorv1alpha1.StatusCondition,
+	conditionType operatorv1alpha1.StatusConditionType,
+	status corev1.ConditionStatus,
+	reason, message string,
+	now metav1.Time,
+) []operatorv1alpha1.StatusCondition {
+	condition := operatorv1alpha1.StatusCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastProbeTime:      now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+	for i := range conditions {
+		if conditions[i].Type != conditionType {
+			continue
+		}
+		if conditions[i].Status == status {
+			condition.LastTransitionTime = conditions[i].LastTransitionTime
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	return append(conditions, condition)
+}
+
+// webhookDegraded returns the Degraded webhook condition of the given
+// Gatekeeper resource if it is true, nil otherwise.
+func webhookDegraded(gatekeeper *operatorv1alpha1.Gatekeeper) *operatorv1alpha1.StatusCondition {
+	for i, c := range gatekeeper.Status.WebhookConditions {
+		if c.Type == operatorv1alpha1.StatusDegraded && c.Status == corev1.ConditionTrue {
+			return &gatekeeper.Status.WebhookConditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+func TestSetStatusCondition(t *testing.T) {
+	g := NewWithT(t)
+	then := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+
+	// test added
+	conditions := setStatusCondition(nil, operatorv1alpha1.StatusReady, corev1.ConditionFalse, "reason", "message", then)
+	g.Expect(conditions).To(HaveLen(1))
+	g.Expect(conditions[0].LastTransitionTime).To(Equal(then))
+
+	// test unchanged status
+	conditions = setStatusCondition(conditions, operatorv1alpha1.StatusReady, corev1.ConditionFalse, "reason", "message", now)
+	g.Expect(conditions).To(HaveLen(1))
+	g.Expect(conditions[0].LastProbeTime).To(Equal(now))
+	g.Expect(conditions[0].LastTransitionTime).To(Equal(then))
+
+	// test changed status
+	conditions = setStatusCondition(conditions, operatorv1alpha1.StatusReady, corev1.ConditionTrue, "reason", "message", now)
+	g.Expect(conditions).To(HaveLen(1))
+	g.Expect(conditions[0].Status).To(Equal(corev1.ConditionTrue))
+	g.Expect(conditions[0].LastTransitionTime).To(Equal(now))
+}
+
+func TestUpdateWebhookStatus(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       defaultGatekeeperCrName,
+			Generation: 2,
+		},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(gatekeeper).
+		WithStatusSubresource(gatekeeper).
+		Build()
+	r := &GatekeeperReconciler{Client: c}
+	checker := WebhookHealthChecker(c)
+	req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// test pending
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, webhookState{failOpen: true})).To(Succeed())
+	clusterGatekeeper := &operatorv1alpha1.Gatekeeper{}
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(gatekeeper), clusterGatekeeper)).To(Succeed())
+	g.Expect(clusterGatekeeper.Status.ObservedGeneration).To(Equal(int64(2)))
+	g.Expect(clusterGatekeeper.Status.AuditConditions).ToNot(BeNil())
+	g.Expect(webhookDegraded(clusterGatekeeper)).ToNot(BeNil())
+	// the webhook is not unhealthy while it is rolled out
+	g.Expect(checker(req)).To(Succeed())
+
+	// test ready
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, webhookState{})).To(Succeed())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(gatekeeper), clusterGatekeeper)).To(Succeed())
+	g.Expect(clusterGatekeeper.Status.WebhookConditions).To(HaveLen(2))
+	g.Expect(webhookDegraded(clusterGatekeeper)).To(BeNil())
+	g.Expect(checker(req)).To(Succeed())
+
+	// test SLO breached
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, webhookState{sloBreach: "breached"})).To(Succeed())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(gatekeeper), clusterGatekeeper)).To(Succeed())
+	degraded := webhookDegraded(clusterGatekeeper)
+	g.Expect(degraded).ToNot(BeNil())
+	g.Expect(degraded.Reason).To(Equal(ConditionReasonSLOBreached))
+	g.Expect(degraded.Message).To(Equal("breached"))
+	g.Expect(checker(req)).ToNot(Succeed())
+
+	// test policy ready gate
+	state := webhookState{failOpen: true, policyNotReady: "1 of 1 constraint templates"}
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, state)).To(Succeed())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(gatekeeper), clusterGatekeeper)).To(Succeed())
+	g.Expect(webhookDegraded(clusterGatekeeper)).To(BeNil())
+	g.Expect(clusterGatekeeper.Status.WebhookConditions).To(ContainElement(And(
+		HaveField("Type", operatorv1alpha1.StatusReady),
+		HaveField("Status", corev1.ConditionFalse),
+		HaveField("Reason", ConditionReasonPolicyNotReady),
+	)))
+
+	// test policy ready gate timed out
+	state.policyTimedOut = true
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, state)).To(Succeed())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(gatekeeper), clusterGatekeeper)).To(Succeed())
+	degraded = webhookDegraded(clusterGatekeeper)
+	g.Expect(degraded).ToNot(BeNil())
+	g.Expect(degraded.Reason).To(Equal(ConditionReasonPolicyNotReady))
+	g.Expect(checker(req)).ToNot(Succeed())
+
+	// test webhook probes
+	gatekeeper.Spec.Webhook = &operatorv1alpha1.WebhookConfig{
+		Prober: &operatorv1alpha1.WebhookProberConfig{},
+	}
+	g.Expect(c.Update(ctx, gatekeeper)).To(Succeed())
+	probes := []operatorv1alpha1.WebhookProbeStatus{{Webhook: ValidationGatekeeperWebhook, Succeeded: true}}
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, webhookState{webhookProbes: probes})).To(Succeed())
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, webhookState{})).To(Succeed())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(gatekeeper), clusterGatekeeper)).To(Succeed())
+	g.Expect(clusterGatekeeper.Status.WebhookProbes).To(HaveLen(1))
+	g.Expect(checker(req)).To(Succeed())
+
+	// test failed webhook probe
+	probes = []operatorv1alpha1.WebhookProbeStatus{{Webhook: ValidationGatekeeperWebhook, Message: "timeout"}}
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, webhookState{failOpen: true, webhookProbes: probes})).To(Succeed())
+	// the probes are expected to fail while the webhook is rolled out
+	g.Expect(checker(req)).To(Succeed())
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, webhookState{})).To(Succeed())
+	g.Expect(checker(req)).ToNot(Succeed())
+	gatekeeper.Spec.Webhook = nil
+	g.Expect(c.Update(ctx, gatekeeper)).To(Succeed())
+	g.Expect(r.updateWebhookStatus(ctx, gatekeeper, webhookState{})).To(Succeed())
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(gatekeeper), clusterGatekeeper)).To(Succeed())
+	g.Expect(clusterGatekeeper.Status.WebhookProbes).To(BeEmpty())
+
+	// test missing Gatekeeper resource
+	g.Expect(c.Delete(ctx, gatekeeper)).To(Succeed())
+	g.Expect(c.Get(ctx, types.NamespacedName{Name: defaultGatekeeperCrName}, clusterGatekeeper)).ToNot(Succeed())
+	g.Expect(checker(req)).To(Succeed())
+}
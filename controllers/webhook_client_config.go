@@ -0,0 +1,356 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+)
+
+const (
+	WebhookLoadBalancerServiceName = "gatekeeper-webhook-service-external"
+	WebhookRouteName               = "gatekeeper-webhook"
+	WebhookServerCertSecretName    = "gatekeeper-webhook-server-cert"
+
+	// webhookLoadBalancerServiceAsset and webhookRouteAsset identify the
+	// webhook exposure resources, which are not applied from a manifest, in
+	// the asset operation metrics.
+	webhookLoadBalancerServiceAsset = "webhook-load-balancer-service"
+	webhookRouteAsset               = "webhook-route"
+)
+
+// webhookExposureAsset returns the asset identifying the given webhook
+// exposure resource.
+func webhookExposureAsset(obj *unstructured.Unstructured) string {
+	if obj.GetKind() == "Route" {
+		return webhookRouteAsset
+	}
+	return webhookLoadBalancerServiceAsset
+}
+
+func webhookURLModeEnabled(webhook *operatorv1alpha1.WebhookConfig) bool {
+	return webhook != nil &&
+		webhook.ClientConfig != nil &&
+		webhook.ClientConfig.Mode != nil &&
+		*webhook.ClientConfig.Mode == operatorv1alpha1.WebhookClientConfigURL
+}
+
+// webhookURLConfig returns the URL configuration if the webhook is
+// configured in URL mode, nil otherwise.
+func webhookURLConfig(webhook *operatorv1alpha1.WebhookConfig) *operatorv1alpha1.WebhookURLConfig {
+	if !webhookURLModeEnabled(webhook) {
+		return nil
+	}
+	return webhook.ClientConfig.URL
+}
+
+func webhookURLPort(urlConfig *operatorv1alpha1.WebhookURLConfig) int32 {
+	if urlConfig.Port != nil {
+		return *urlConfig.Port
+	}
+	return defaultWebhookURLPort
+}
+
+func webhookURL(urlConfig *operatorv1alpha1.WebhookURLConfig, path string) string {
+	hostPort := net.JoinHostPort(urlConfig.Host, strconv.Itoa(int(webhookURLPort(urlConfig))))
+	return fmt.Sprintf("https://%s%s", hostPort, path)
+}
+
+func webhookExposeMode(webhook *operatorv1alpha1.WebhookConfig) operatorv1alpha1.WebhookExposeMode {
+	urlConfig := webhookURLConfig(webhook)
+	if urlConfig == nil || urlConfig.Expose == nil {
+		return operatorv1alpha1.WebhookExposeNone
+	}
+	return *urlConfig.Expose
+}
+
+// setWebhookServerCertData sets operator generated certificates in the
+// webhook server certificate Secret when the webhook is configured in URL
+// mode. Existing certificates are reused as long as they are valid for the
+// configured host and are not about to expire.
+func (r *GatekeeperReconciler) setWebhookServerCertData(ctx context.Context, obj *unstructured.Unstructured, webhook *operatorv1alpha1.WebhookConfig) error {
+	urlConfig := webhookURLConfig(webhook)
+	if urlConfig == nil {
+		return nil
+	}
+
+	data, err := r.getWebhookServerCertData(ctx, obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	hosts := webhookCertHosts(r.gatekeeperNamespace(), urlConfig.Host)
+	if !webhookCertificatesValid(data, hosts, now) {
+		data, err = generateWebhookCertificates(hosts, now)
+		if err != nil {
+			return err
+		}
+		r.Log.Info("Generated webhook server certificates", "hosts", hosts)
+	}
+
+	encoded := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		encoded[k] = base64.StdEncoding.EncodeToString(v)
+	}
+	if err := unstructured.SetNestedMap(obj.Object, encoded, "data"); err != nil {
+		return errors.Wrapf(err, "Failed to set webhook server certificate data")
+	}
+	return nil
+}
+
+// setWebhookCABundle injects the CA of the operator generated certificates
+// into each webhook's clientConfig when the webhook is configured in URL
+// mode, since Gatekeeper's certificate rotation is disabled in that case.
+func (r *GatekeeperReconciler) setWebhookCABundle(ctx context.Context, obj *unstructured.Unstructured, webhook *operatorv1alpha1.WebhookConfig) error {
+	if webhookURLConfig(webhook) == nil {
+		return nil
+	}
+
+	data, err := r.getWebhookServerCertData(ctx, r.gatekeeperNamespace(), WebhookServerCertSecretName)
+	if err != nil {
+		return err
+	}
+	caBundle := data[CACertName]
+	if len(caBundle) == 0 {
+		return fmt.Errorf("webhook server certificate Secret %s/%s does not contain %s", r.gatekeeperNamespace(), WebhookServerCertSecretName, CACertName)
+	}
+
+	webhooks, found, err := unstructured.NestedSlice(obj.Object, "webhooks")
+	if err != nil || !found {
+		return errors.Wrapf(err, "Failed to retrieve webhooks definition")
+	}
+	for _, w := range webhooks {
+		webhook := w.(map[string]interface{})
+		err := unstructured.SetNestedField(webhook, base64.StdEncoding.EncodeToString(caBundle), "clientConfig", "caBundle")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to set webhook clientConfig.caBundle")
+		}
+	}
+	if err := unstructured.SetNestedSlice(obj.Object, webhooks, "webhooks"); err != nil {
+		return errors.Wrapf(err, "Failed to set webhooks")
+	}
+	return nil
+}
+
+// getWebhookServerCertData returns the decoded data of the webhook server
+// certificate Secret in the cluster, or nil if it does not exist yet.
+func (r *GatekeeperReconciler) getWebhookServerCertData(ctx context.Context, namespace, name string) (map[string][]byte, error) {
+	secret := &unstructured.Unstructured{}
+	secret.SetAPIVersion("v1")
+	secret.SetKind("Secret")
+	namespacedName := types.NamespacedName{
+		Namespace: namespace,
+		Name:      name,
+	}
+
+	err := r.Get(ctx, namespacedName, secret)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "Error attempting to get resource %s", namespacedName)
+	}
+
+	encoded, _, err := unstructured.NestedStringMap(secret.Object, "data")
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error retrieving data from secret %s", namespacedName)
+	}
+	data := make(map[string][]byte, len(encoded))
+	for k, v := range encoded {
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error decoding %s from secret %s", k, namespacedName)
+		}
+		data[k] = decoded
+	}
+	return data, nil
+}
+
+// applyWebhookExposure creates the resources that expose the webhook at the
+// configured URL and removes the ones that are no longer needed.
+func (r *GatekeeperReconciler) applyWebhookExposure(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper) error {
+	exposeMode := webhookExposeMode(gatekeeper.Spec.Webhook)
+	if exposeMode == operatorv1alpha1.WebhookExposeRoute && !r.isOpenShift() {
+		return fmt.Errorf("webhook expose mode %s is only supported on OpenShift", exposeMode)
+	}
+
+	// The router only serves passthrough Routes on the default HTTPS port.
+	if exposeMode == operatorv1alpha1.WebhookExposeRoute && webhookURLPort(gatekeeper.Spec.Webhook.ClientConfig.URL) != defaultWebhookURLPort {
+		return fmt.Errorf("webhook expose mode %s only supports port %d", exposeMode, defaultWebhookURLPort)
+	}
+
+	service := webhookLoadBalancerService(r.gatekeeperNamespace(), defaultWebhookURLPort, "")
+	serviceOperation := delete
+	if exposeMode == operatorv1alpha1.WebhookExposeLoadBalancer {
+		urlConfig := gatekeeper.Spec.Webhook.ClientConfig.URL
+		service = webhookLoadBalancerService(r.gatekeeperNamespace(), webhookURLPort(urlConfig), urlConfig.Host)
+		serviceOperation = apply
+		setCommonMetadata(service, gatekeeper.Spec)
+		setInventoryLabels(service, gatekeeper)
+	}
+	if err := r.crudResource(ctx, webhookLoadBalancerServiceAsset, service, gatekeeper, serviceOperation); err != nil {
+		return err
+	}
+	if serviceOperation == apply {
+		if err := r.checkLoadBalancerIngress(ctx, gatekeeper, gatekeeper.Spec.Webhook.ClientConfig.URL.Host); err != nil {
+			return err
+		}
+	}
+
+	// The Route API is only available on OpenShift.
+	if !r.isOpenShift() {
+		return nil
+	}
+	route := webhookRoute(r.gatekeeperNamespace(), "")
+	routeOperation := delete
+	if exposeMode == operatorv1alpha1.WebhookExposeRoute {
+		route = webhookRoute(r.gatekeeperNamespace(), gatekeeper.Spec.Webhook.ClientConfig.URL.Host)
+		routeOperation = apply
+		setCommonMetadata(route, gatekeeper.Spec)
+		setInventoryLabels(route, gatekeeper)
+	}
+	return r.crudResource(ctx, webhookRouteAsset, route, gatekeeper, routeOperation)
+}
+
+// checkLoadBalancerIngress records a warning event if the load balancer of
+// the webhook was provisioned at addresses that do not include the webhook
+// URL host. Only IP hosts are checked since host names may resolve to the
+// load balancer through DNS records the operator does not know about.
+func (r *GatekeeperReconciler) checkLoadBalancerIngress(ctx context.Context, gatekeeper *operatorv1alpha1.Gatekeeper, host string) error {
+	service := &unstructured.Unstructured{}
+	service.SetAPIVersion("v1")
+	service.SetKind("Service")
+	namespacedName := types.NamespacedName{Namespace: r.gatekeeperNamespace(), Name: WebhookLoadBalancerServiceName}
+	if err := r.Get(ctx, namespacedName, service); err != nil {
+		return errors.Wrapf(err, "Error attempting to get resource %s", namespacedName)
+	}
+	ingress, _, _ := unstructured.NestedSlice(service.Object, "status", "loadBalancer", "ingress")
+	var addresses []string
+	for _, i := range ingress {
+		entry, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"ip", "hostname"} {
+			if address, _ := entry[key].(string); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		r.Log.Info("Waiting for the webhook load balancer to be provisioned", "service", namespacedName)
+		return nil
+	}
+	hostIP := net.ParseIP(host)
+	if hostIP == nil {
+		r.Log.Info("Webhook load balancer provisioned", "service", namespacedName, "addresses", addresses, "host", host)
+		return nil
+	}
+	for _, address := range addresses {
+		if hostIP.Equal(net.ParseIP(address)) {
+			return nil
+		}
+	}
+	r.recordEvent(gatekeeper, corev1.EventTypeWarning, EventReasonWebhookHostMismatch,
+		"Load balancer %s was provisioned at %s, which does not include the webhook URL host %s",
+		namespacedName, strings.Join(addresses, ", "), host)
+	return nil
+}
+
+// webhookLoadBalancerService returns a LoadBalancer Service exposing the
+// webhook pods on the given port. The load balancer IP is requested if the
+// given host is an IP address.
+func webhookLoadBalancerService(namespace string, port int32, host string) *unstructured.Unstructured {
+	service := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata": map[string]interface{}{
+				"name":      WebhookLoadBalancerServiceName,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					"gatekeeper.sh/system": "yes",
+				},
+			},
+			"spec": map[string]interface{}{
+				"type": "LoadBalancer",
+				"ports": []interface{}{
+					map[string]interface{}{
+						"name":       WebhookServicePortName,
+						"port":       int64(port),
+						"targetPort": "webhook-server",
+					},
+				},
+				"selector": map[string]interface{}{
+					"control-plane":           "controller-manager",
+					"gatekeeper.sh/operation": "webhook",
+					"gatekeeper.sh/system":    "yes",
+				},
+			},
+		},
+	}
+	if net.ParseIP(host) != nil {
+		_ = unstructured.SetNestedField(service.Object, host, "spec", "loadBalancerIP")
+	}
+	return service
+}
+
+// webhookRoute returns an OpenShift passthrough Route exposing the webhook
+// Service at the given host.
+func webhookRoute(namespace, host string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "route.openshift.io/v1",
+			"kind":       "Route",
+			"metadata": map[string]interface{}{
+				"name":      WebhookRouteName,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					"gatekeeper.sh/system": "yes",
+				},
+			},
+			"spec": map[string]interface{}{
+				"host": host,
+				"port": map[string]interface{}{
+					"targetPort": WebhookServicePortName,
+				},
+				"tls": map[string]interface{}{
+					"termination":                   "passthrough",
+					"insecureEdgeTerminationPolicy": "None",
+				},
+				"to": map[string]interface{}{
+					"kind": "Service",
+					"name": WebhookServiceName,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/platform"
+)
+
+func TestApplyWebhookExposure(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(operatorv1alpha1.AddToScheme(scheme)).To(Succeed())
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &GatekeeperReconciler{Client: c, Log: ctrl.Log, Scheme: scheme, Namespace: namespace, namespace: namespace, Recorder: recorder}
+	urlMode := operatorv1alpha1.WebhookClientConfigURL
+	loadBalancer := operatorv1alpha1.WebhookExposeLoadBalancer
+	gatekeeper := &operatorv1alpha1.Gatekeeper{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultGatekeeperCrName,
+		},
+		Spec: operatorv1alpha1.GatekeeperSpec{
+			Webhook: &operatorv1alpha1.WebhookConfig{
+				ClientConfig: &operatorv1alpha1.WebhookClientConfig{
+					Mode: &urlMode,
+					URL: &operatorv1alpha1.WebhookURLConfig{
+						Host:   "192.0.2.10",
+						Expose: &loadBalancer,
+					},
+				},
+			},
+		},
+	}
+
+	// test the load balancer IP is requested for an IP host
+	g.Expect(r.applyWebhookExposure(ctx, gatekeeper)).To(Succeed())
+	service := &corev1.Service{}
+	g.Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: WebhookLoadBalancerServiceName}, service)).To(Succeed())
+	g.Expect(service.Spec.LoadBalancerIP).To(Equal("192.0.2.10"))
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Normal Created")))
+
+	// test a load balancer provisioned at another address
+	service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "198.51.100.20"}}
+	g.Expect(c.Status().Update(ctx, service)).To(Succeed())
+	g.Expect(r.applyWebhookExposure(ctx, gatekeeper)).To(Succeed())
+	g.Expect(recorder.Events).To(Receive(HavePrefix("Warning WebhookHostMismatch")))
+
+	// test a load balancer provisioned at the host
+	service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "192.0.2.10"}}
+	g.Expect(c.Status().Update(ctx, service)).To(Succeed())
+	g.Expect(r.applyWebhookExposure(ctx, gatekeeper)).To(Succeed())
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// test Routes only support the default port
+	route := operatorv1alpha1.WebhookExposeRoute
+	port := int32(8443)
+	gatekeeper.Spec.Webhook.ClientConfig.URL.Expose = &route
+	gatekeeper.Spec.Webhook.ClientConfig.URL.Port = &port
+	r.PlatformInfo = platform.PlatformInfo{Name: platform.OpenShift}
+	g.Expect(r.applyWebhookExposure(ctx, gatekeeper)).To(MatchError(ContainSubstring("only supports port 443")))
+}
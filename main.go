@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -35,6 +36,7 @@ import (
 	operatorv1alpha1 "github.com/gatekeeper/gatekeeper-operator/api/v1alpha1"
 	"github.com/gatekeeper/gatekeeper-operator/controllers"
 	"github.com/gatekeeper/gatekeeper-operator/pkg/platform"
+	"github.com/gatekeeper/gatekeeper-operator/pkg/tracing"
 	"github.com/gatekeeper/gatekeeper-operator/pkg/util"
 	"github.com/gatekeeper/gatekeeper-operator/pkg/version"
 	"github.com/pkg/errors"
@@ -57,11 +59,21 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var enableWebhookHealthCheck bool
+	var tracingConfig tracing.Config
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhookHealthCheck, "webhook-health-check", false,
+		"Enable a readiness check that fails while the Gatekeeper webhook is unhealthy.")
+	flag.StringVar(&tracingConfig.Endpoint, "otlp-endpoint", "",
+		"The host:port of the OTLP gRPC receiver to export traces to. Tracing is disabled if empty.")
+	flag.BoolVar(&tracingConfig.Insecure, "otlp-insecure", false,
+		"Disable TLS for the connection to the OTLP receiver.")
+	flag.Float64Var(&tracingConfig.SampleRatio, "otlp-sample-ratio", 1,
+		"The ratio of reconciliations that are traced, between 0 and 1.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -72,7 +84,31 @@ func main() {
 
 	ctrl.Log.WithName("Gatekeeper Operator version").Info(fmt.Sprintf("%#v", version.Get()))
 
-	cfg := ctrl.GetConfigOrDie()
+	ctx := ctrl.SetupSignalHandler()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracingConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	// The pending spans are flushed before exiting, since deferred functions
+	// are not run by os.Exit.
+	stopTracing := func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "unable to shut down tracing")
+		}
+	}
+	exit := func(code int) {
+		stopTracing()
+		os.Exit(code)
+	}
+	defer stopTracing()
+
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		setupLog.Error(err, "unable to get kubeconfig")
+		exit(1)
+	}
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -83,46 +119,68 @@ func main() {
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
-		os.Exit(1)
+		exit(1)
 	}
 
 	platformInfo, err := platform.GetPlatformInfo(cfg)
 	if err != nil {
 		setupLog.Error(err, "unable to get platform name")
-		os.Exit(1)
+		exit(1)
 	}
 
 	namespace, err := gatekeeperNamespace(platformInfo)
 	if err != nil {
 		setupLog.Error(err, "unable to get Gatekeeper namespace")
-		os.Exit(1)
+		exit(1)
+	}
+
+	// The operator namespace is unknown when running outside of a cluster, in
+	// which case the operator metrics are not monitored.
+	operatorNamespace, err := util.GetOperatorNamespace()
+	if err != nil {
+		setupLog.Info("Unable to get the operator namespace, the operator metrics will not be monitored", "reason", err.Error())
+	}
+
+	profileFetcher, err := controllers.NewPortForwardProfileFetcher(cfg)
+	if err != nil {
+		setupLog.Error(err, "unable to set up profile capture")
+		exit(1)
 	}
 
 	if err = (&controllers.GatekeeperReconciler{
-		Client:       mgr.GetClient(),
-		Log:          ctrl.Log.WithName("controllers").WithName("Gatekeeper"),
-		Scheme:       mgr.GetScheme(),
-		Namespace:    namespace,
-		PlatformInfo: platformInfo,
+		Client:            tracing.WrapClient(mgr.GetClient()),
+		Log:               ctrl.Log.WithName("controllers").WithName("Gatekeeper"),
+		Scheme:            mgr.GetScheme(),
+		Namespace:         namespace,
+		OperatorNamespace: operatorNamespace,
+		PlatformInfo:      platformInfo,
+		Recorder:          mgr.GetEventRecorderFor("gatekeeper-operator"),
+		ProfileFetcher:    profileFetcher,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Gatekeeper")
-		os.Exit(1)
+		exit(1)
 	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
-		os.Exit(1)
+		exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := mgr.AddReadyzCheck("readyz", controllers.CacheSyncChecker(mgr.GetCache())); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
-		os.Exit(1)
+		exit(1)
+	}
+	if enableWebhookHealthCheck {
+		if err := mgr.AddReadyzCheck("gatekeeper-webhook", controllers.WebhookHealthChecker(mgr.GetClient())); err != nil {
+			setupLog.Error(err, "unable to set up Gatekeeper webhook ready check")
+			exit(1)
+		}
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
+		exit(1)
 	}
 }
 
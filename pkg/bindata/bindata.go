@@ -14,14 +14,20 @@
 // config/gatekeeper-rendered/apiextensions.k8s.io_v1_customresourcedefinition_providers.externaldata.gatekeeper.sh.yaml
 // config/gatekeeper-rendered/apps_v1_deployment_gatekeeper-audit.yaml
 // config/gatekeeper-rendered/apps_v1_deployment_gatekeeper-controller-manager.yaml
+// config/gatekeeper-rendered/monitoring.coreos.com_v1_prometheusrule_gatekeeper-alerts.yaml
+// config/gatekeeper-rendered/monitoring.coreos.com_v1_servicemonitor_gatekeeper-metrics.yaml
 // config/gatekeeper-rendered/policy_v1_poddisruptionbudget_gatekeeper-controller-manager.yaml
 // config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_clusterrole_gatekeeper-manager-role.yaml
 // config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_clusterrolebinding_gatekeeper-manager-rolebinding.yaml
 // config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_role_gatekeeper-manager-role.yaml
+// config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_role_gatekeeper-prometheus-k8s.yaml
 // config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-manager-rolebinding.yaml
+// config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-prometheus-k8s.yaml
+// config/gatekeeper-rendered/v1_configmap_gatekeeper-dashboard.yaml
 // config/gatekeeper-rendered/v1_namespace_gatekeeper-system.yaml
 // config/gatekeeper-rendered/v1_resourcequota_gatekeeper-critical-pods.yaml
 // config/gatekeeper-rendered/v1_secret_gatekeeper-webhook-server-cert.yaml
+// config/gatekeeper-rendered/v1_service_gatekeeper-metrics-service.yaml
 // config/gatekeeper-rendered/v1_service_gatekeeper-webhook-service.yaml
 // config/gatekeeper-rendered/v1_serviceaccount_gatekeeper-admin.yaml
 package bindata
@@ -4455,6 +4461,139 @@ func configGatekeeperRenderedApps_v1_deployment_gatekeeperControllerManagerYaml(
 	return a, nil
 }
 
+var _configGatekeeperRenderedMonitoringCoreosCom_v1_prometheusrule_gatekeeperAlertsYaml = []byte(`apiVersion: monitoring.coreos.com/v1
+kind: PrometheusRule
+metadata:
+  labels:
+    gatekeeper.sh/system: "yes"
+  name: gatekeeper-alerts
+  namespace: gatekeeper-system
+spec:
+  groups:
+  - name: gatekeeper-webhook
+    rules:
+    - alert: GatekeeperWebhookErrorRateHigh
+      annotations:
+        description: More than 5% of the admission requests handled by the Gatekeeper
+          webhook in namespace {{ $labels.namespace }} failed with an error over the
+          last 10 minutes.
+        summary: Gatekeeper webhook error rate is high.
+      expr: |
+        sum by (namespace) (rate(gatekeeper_validation_request_count{admission_status="error"}[5m]))
+          /
+        sum by (namespace) (rate(gatekeeper_validation_request_count[5m])) > 0.05
+      for: 10m
+      labels:
+        severity: warning
+    - alert: GatekeeperWebhookLatencyHigh
+      annotations:
+        description: The 99th percentile latency of the admission requests handled
+          by the Gatekeeper webhook in namespace {{ $labels.namespace }} is {{ $value
+          | humanizeDuration }}.
+        summary: Gatekeeper webhook latency is high.
+      expr: |
+        histogram_quantile(0.99, sum by (namespace, le) (rate(gatekeeper_validation_request_duration_seconds_bucket[5m]))) > 1
+      for: 10m
+      labels:
+        severity: warning
+    - alert: GatekeeperWebhookCertificateExpiringSoon
+      annotations:
+        description: The Gatekeeper webhook serving certificate expires in {{ $value
+          | humanizeDuration }}. This alert requires the metrics of the Gatekeeper
+          operator to be scraped through the gatekeeper-operator-metrics ServiceMonitor.
+        summary: Gatekeeper webhook certificate is about to expire.
+      expr: |
+        gatekeeper_operator_webhook_certificate_expiry_timestamp_seconds - time() < 7 * 24 * 3600
+      for: 1h
+      labels:
+        severity: warning
+  - name: gatekeeper-audit
+    rules:
+    - alert: GatekeeperAuditDurationHigh
+      annotations:
+        description: The 99th percentile duration of the Gatekeeper audit runs in
+          namespace {{ $labels.namespace }} is {{ $value | humanizeDuration }}.
+        summary: Gatekeeper audit runs take too long.
+      expr: |
+        histogram_quantile(0.99, sum by (namespace, le) (rate(gatekeeper_audit_duration_seconds_bucket[1h]))) > 600
+      for: 1h
+      labels:
+        severity: warning
+    - alert: GatekeeperAuditNotRunning
+      annotations:
+        description: The Gatekeeper audit in namespace {{ $labels.namespace }} has
+          not completed a run in the last hour.
+        summary: Gatekeeper audit is not running.
+      expr: |
+        time() - max by (namespace) (gatekeeper_audit_last_run_time) > 3600
+      for: 15m
+      labels:
+        severity: warning
+  - name: gatekeeper-constraint-templates
+    rules:
+    - alert: GatekeeperConstraintTemplateIngestionErrors
+      annotations:
+        description: Gatekeeper in namespace {{ $labels.namespace }} failed to ingest
+          constraint templates in the last 10 minutes.
+        summary: Gatekeeper constraint templates failed to be ingested.
+      expr: |
+        sum by (namespace) (increase(gatekeeper_constraint_template_ingestion_count{status="error"}[10m])) > 0
+      labels:
+        severity: warning
+`)
+
+func configGatekeeperRenderedMonitoringCoreosCom_v1_prometheusrule_gatekeeperAlertsYamlBytes() ([]byte, error) {
+	return _configGatekeeperRenderedMonitoringCoreosCom_v1_prometheusrule_gatekeeperAlertsYaml, nil
+}
+
+func configGatekeeperRenderedMonitoringCoreosCom_v1_prometheusrule_gatekeeperAlertsYaml() (*asset, error) {
+	bytes, err := configGatekeeperRenderedMonitoringCoreosCom_v1_prometheusrule_gatekeeperAlertsYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "config/gatekeeper-rendered/monitoring.coreos.com_v1_prometheusrule_gatekeeper-alerts.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _configGatekeeperRenderedMonitoringCoreosCom_v1_servicemonitor_gatekeeperMetricsYaml = []byte(`apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  labels:
+    gatekeeper.sh/system: "yes"
+  name: gatekeeper-metrics
+  namespace: gatekeeper-system
+spec:
+  endpoints:
+  - interval: 30s
+    path: /metrics
+    port: metrics
+    relabelings:
+    - action: replace
+      sourceLabels:
+      - __meta_kubernetes_pod_label_gatekeeper_sh_operation
+      targetLabel: operation
+  selector:
+    matchLabels:
+      operator.gatekeeper.sh/metrics: "true"
+`)
+
+func configGatekeeperRenderedMonitoringCoreosCom_v1_servicemonitor_gatekeeperMetricsYamlBytes() ([]byte, error) {
+	return _configGatekeeperRenderedMonitoringCoreosCom_v1_servicemonitor_gatekeeperMetricsYaml, nil
+}
+
+func configGatekeeperRenderedMonitoringCoreosCom_v1_servicemonitor_gatekeeperMetricsYaml() (*asset, error) {
+	bytes, err := configGatekeeperRenderedMonitoringCoreosCom_v1_servicemonitor_gatekeeperMetricsYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "config/gatekeeper-rendered/monitoring.coreos.com_v1_servicemonitor_gatekeeper-metrics.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _configGatekeeperRenderedPolicy_v1_poddisruptionbudget_gatekeeperControllerManagerYaml = []byte(`apiVersion: policy/v1
 kind: PodDisruptionBudget
 metadata:
@@ -4738,6 +4877,41 @@ func configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperManagerRol
 	return a, nil
 }
 
+var _configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperPrometheusK8sYaml = []byte(`apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  labels:
+    gatekeeper.sh/system: "yes"
+  name: gatekeeper-prometheus-k8s
+  namespace: gatekeeper-system
+rules:
+- apiGroups:
+  - ""
+  resources:
+  - endpoints
+  - pods
+  - services
+  verbs:
+  - get
+  - list
+  - watch
+`)
+
+func configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperPrometheusK8sYamlBytes() ([]byte, error) {
+	return _configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperPrometheusK8sYaml, nil
+}
+
+func configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperPrometheusK8sYaml() (*asset, error) {
+	bytes, err := configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperPrometheusK8sYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_role_gatekeeper-prometheus-k8s.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperManagerRolebindingYaml = []byte(`apiVersion: rbac.authorization.k8s.io/v1
 kind: RoleBinding
 metadata:
@@ -4770,6 +4944,331 @@ func configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperMan
 	return a, nil
 }
 
+var _configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperPrometheusK8sYaml = []byte(`apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  labels:
+    gatekeeper.sh/system: "yes"
+  name: gatekeeper-prometheus-k8s
+  namespace: gatekeeper-system
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: gatekeeper-prometheus-k8s
+subjects:
+- kind: ServiceAccount
+  name: prometheus-k8s
+  namespace: openshift-monitoring
+`)
+
+func configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperPrometheusK8sYamlBytes() ([]byte, error) {
+	return _configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperPrometheusK8sYaml, nil
+}
+
+func configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperPrometheusK8sYaml() (*asset, error) {
+	bytes, err := configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperPrometheusK8sYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-prometheus-k8s.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _configGatekeeperRenderedV1_configmap_gatekeeperDashboardYaml = []byte(`apiVersion: v1
+data:
+  gatekeeper.json: |
+    {
+      "editable": false,
+      "panels": [
+        {
+          "datasource": {
+            "type": "prometheus",
+            "uid": "${datasource}"
+          },
+          "fieldConfig": {
+            "defaults": {
+              "unit": "reqps"
+            },
+            "overrides": []
+          },
+          "gridPos": {
+            "h": 8,
+            "w": 12,
+            "x": 0,
+            "y": 0
+          },
+          "id": 1,
+          "targets": [
+            {
+              "expr": "sum by (admission_status) (rate(gatekeeper_validation_request_count{namespace=\"$namespace\"}[5m]))",
+              "legendFormat": "{{admission_status}}",
+              "refId": "A"
+            }
+          ],
+          "title": "Validation requests",
+          "type": "timeseries"
+        },
+        {
+          "datasource": {
+            "type": "prometheus",
+            "uid": "${datasource}"
+          },
+          "fieldConfig": {
+            "defaults": {
+              "unit": "s"
+            },
+            "overrides": []
+          },
+          "gridPos": {
+            "h": 8,
+            "w": 12,
+            "x": 12,
+            "y": 0
+          },
+          "id": 2,
+          "targets": [
+            {
+              "expr": "histogram_quantile(0.99, sum by (le) (rate(gatekeeper_validation_request_duration_seconds_bucket{namespace=\"$namespace\"}[5m])))",
+              "legendFormat": "p99",
+              "refId": "A"
+            },
+            {
+              "expr": "histogram_quantile(0.5, sum by (le) (rate(gatekeeper_validation_request_duration_seconds_bucket{namespace=\"$namespace\"}[5m])))",
+              "legendFormat": "p50",
+              "refId": "B"
+            }
+          ],
+          "title": "Validation request latency",
+          "type": "timeseries"
+        },
+        {
+          "datasource": {
+            "type": "prometheus",
+            "uid": "${datasource}"
+          },
+          "fieldConfig": {
+            "defaults": {
+              "unit": "reqps"
+            },
+            "overrides": []
+          },
+          "gridPos": {
+            "h": 8,
+            "w": 12,
+            "x": 0,
+            "y": 8
+          },
+          "id": 3,
+          "targets": [
+            {
+              "expr": "sum by (mutation_status) (rate(gatekeeper_mutation_request_count{namespace=\"$namespace\"}[5m]))",
+              "legendFormat": "{{mutation_status}}",
+              "refId": "A"
+            }
+          ],
+          "title": "Mutation requests",
+          "type": "timeseries"
+        },
+        {
+          "datasource": {
+            "type": "prometheus",
+            "uid": "${datasource}"
+          },
+          "fieldConfig": {
+            "defaults": {
+              "unit": "s"
+            },
+            "overrides": []
+          },
+          "gridPos": {
+            "h": 8,
+            "w": 12,
+            "x": 12,
+            "y": 8
+          },
+          "id": 4,
+          "targets": [
+            {
+              "expr": "histogram_quantile(0.99, sum by (le) (rate(gatekeeper_mutation_request_duration_seconds_bucket{namespace=\"$namespace\"}[5m])))",
+              "legendFormat": "p99",
+              "refId": "A"
+            }
+          ],
+          "title": "Mutation request latency",
+          "type": "timeseries"
+        },
+        {
+          "datasource": {
+            "type": "prometheus",
+            "uid": "${datasource}"
+          },
+          "fieldConfig": {
+            "defaults": {
+              "unit": "s"
+            },
+            "overrides": []
+          },
+          "gridPos": {
+            "h": 8,
+            "w": 12,
+            "x": 0,
+            "y": 16
+          },
+          "id": 5,
+          "targets": [
+            {
+              "expr": "histogram_quantile(0.99, sum by (le) (rate(gatekeeper_audit_duration_seconds_bucket{namespace=\"$namespace\"}[1h])))",
+              "legendFormat": "p99",
+              "refId": "A"
+            }
+          ],
+          "title": "Audit duration",
+          "type": "timeseries"
+        },
+        {
+          "datasource": {
+            "type": "prometheus",
+            "uid": "${datasource}"
+          },
+          "fieldConfig": {
+            "defaults": {
+              "unit": "short"
+            },
+            "overrides": []
+          },
+          "gridPos": {
+            "h": 8,
+            "w": 12,
+            "x": 12,
+            "y": 16
+          },
+          "id": 6,
+          "targets": [
+            {
+              "expr": "sum by (enforcement_action) (gatekeeper_violations{namespace=\"$namespace\"})",
+              "legendFormat": "{{enforcement_action}}",
+              "refId": "A"
+            }
+          ],
+          "title": "Violations",
+          "type": "timeseries"
+        },
+        {
+          "datasource": {
+            "type": "prometheus",
+            "uid": "${datasource}"
+          },
+          "fieldConfig": {
+            "defaults": {
+              "unit": "short"
+            },
+            "overrides": []
+          },
+          "gridPos": {
+            "h": 8,
+            "w": 12,
+            "x": 0,
+            "y": 24
+          },
+          "id": 7,
+          "targets": [
+            {
+              "expr": "sum by (status) (gatekeeper_constraint_templates{namespace=\"$namespace\"})",
+              "legendFormat": "{{status}}",
+              "refId": "A"
+            }
+          ],
+          "title": "Constraint templates",
+          "type": "timeseries"
+        },
+        {
+          "datasource": {
+            "type": "prometheus",
+            "uid": "${datasource}"
+          },
+          "fieldConfig": {
+            "defaults": {
+              "unit": "short"
+            },
+            "overrides": []
+          },
+          "gridPos": {
+            "h": 8,
+            "w": 12,
+            "x": 12,
+            "y": 24
+          },
+          "id": 8,
+          "targets": [
+            {
+              "expr": "sum by (enforcement_action, status) (gatekeeper_constraints{namespace=\"$namespace\"})",
+              "legendFormat": "{{enforcement_action}} {{status}}",
+              "refId": "A"
+            }
+          ],
+          "title": "Constraints",
+          "type": "timeseries"
+        }
+      ],
+      "refresh": "1m",
+      "schemaVersion": 37,
+      "tags": [
+        "gatekeeper"
+      ],
+      "templating": {
+        "list": [
+          {
+            "label": "Data source",
+            "name": "datasource",
+            "query": "prometheus",
+            "type": "datasource"
+          },
+          {
+            "datasource": {
+              "type": "prometheus",
+              "uid": "${datasource}"
+            },
+            "label": "Namespace",
+            "name": "namespace",
+            "query": "label_values(gatekeeper_validation_request_count, namespace)",
+            "refresh": 2,
+            "type": "query"
+          }
+        ]
+      },
+      "time": {
+        "from": "now-6h",
+        "to": "now"
+      },
+      "title": "Gatekeeper",
+      "uid": "gatekeeper"
+    }
+kind: ConfigMap
+metadata:
+  labels:
+    gatekeeper.sh/system: "yes"
+    grafana_dashboard: "1"
+  name: gatekeeper-dashboard
+  namespace: gatekeeper-system
+`)
+
+func configGatekeeperRenderedV1_configmap_gatekeeperDashboardYamlBytes() ([]byte, error) {
+	return _configGatekeeperRenderedV1_configmap_gatekeeperDashboardYaml, nil
+}
+
+func configGatekeeperRenderedV1_configmap_gatekeeperDashboardYaml() (*asset, error) {
+	bytes, err := configGatekeeperRenderedV1_configmap_gatekeeperDashboardYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "config/gatekeeper-rendered/v1_configmap_gatekeeper-dashboard.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _configGatekeeperRenderedV1_namespace_gatekeeperSystemYaml = []byte(`apiVersion: v1
 kind: Namespace
 metadata:
@@ -4858,6 +5357,38 @@ func configGatekeeperRenderedV1_secret_gatekeeperWebhookServerCertYaml() (*asset
 	return a, nil
 }
 
+var _configGatekeeperRenderedV1_service_gatekeeperMetricsServiceYaml = []byte(`apiVersion: v1
+kind: Service
+metadata:
+  labels:
+    gatekeeper.sh/system: "yes"
+    operator.gatekeeper.sh/metrics: "true"
+  name: gatekeeper-metrics-service
+  namespace: gatekeeper-system
+spec:
+  ports:
+  - name: metrics
+    port: 8888
+    targetPort: metrics
+  selector:
+    gatekeeper.sh/system: "yes"
+`)
+
+func configGatekeeperRenderedV1_service_gatekeeperMetricsServiceYamlBytes() ([]byte, error) {
+	return _configGatekeeperRenderedV1_service_gatekeeperMetricsServiceYaml, nil
+}
+
+func configGatekeeperRenderedV1_service_gatekeeperMetricsServiceYaml() (*asset, error) {
+	bytes, err := configGatekeeperRenderedV1_service_gatekeeperMetricsServiceYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "config/gatekeeper-rendered/v1_service_gatekeeper-metrics-service.yaml", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _configGatekeeperRenderedV1_service_gatekeeperWebhookServiceYaml = []byte(`apiVersion: v1
 kind: Service
 metadata:
@@ -4981,14 +5512,20 @@ var _bindata = map[string]func() (*asset, error){
 	"config/gatekeeper-rendered/apiextensions.k8s.io_v1_customresourcedefinition_providers.externaldata.gatekeeper.sh.yaml":                      configGatekeeperRenderedApiextensionsK8sIo_v1_customresourcedefinition_providersExternaldataGatekeeperShYaml,
 	"config/gatekeeper-rendered/apps_v1_deployment_gatekeeper-audit.yaml":                                                                        configGatekeeperRenderedApps_v1_deployment_gatekeeperAuditYaml,
 	"config/gatekeeper-rendered/apps_v1_deployment_gatekeeper-controller-manager.yaml":                                                           configGatekeeperRenderedApps_v1_deployment_gatekeeperControllerManagerYaml,
+	"config/gatekeeper-rendered/monitoring.coreos.com_v1_prometheusrule_gatekeeper-alerts.yaml":                                                  configGatekeeperRenderedMonitoringCoreosCom_v1_prometheusrule_gatekeeperAlertsYaml,
+	"config/gatekeeper-rendered/monitoring.coreos.com_v1_servicemonitor_gatekeeper-metrics.yaml":                                                 configGatekeeperRenderedMonitoringCoreosCom_v1_servicemonitor_gatekeeperMetricsYaml,
 	"config/gatekeeper-rendered/policy_v1_poddisruptionbudget_gatekeeper-controller-manager.yaml":                                                configGatekeeperRenderedPolicy_v1_poddisruptionbudget_gatekeeperControllerManagerYaml,
 	"config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_clusterrole_gatekeeper-manager-role.yaml":                                           configGatekeeperRenderedRbacAuthorizationK8sIo_v1_clusterrole_gatekeeperManagerRoleYaml,
 	"config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_clusterrolebinding_gatekeeper-manager-rolebinding.yaml":                             configGatekeeperRenderedRbacAuthorizationK8sIo_v1_clusterrolebinding_gatekeeperManagerRolebindingYaml,
 	"config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_role_gatekeeper-manager-role.yaml":                                                  configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperManagerRoleYaml,
+	"config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_role_gatekeeper-prometheus-k8s.yaml":                                                configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperPrometheusK8sYaml,
 	"config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-manager-rolebinding.yaml":                                    configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperManagerRolebindingYaml,
+	"config/gatekeeper-rendered/rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-prometheus-k8s.yaml":                                         configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperPrometheusK8sYaml,
+	"config/gatekeeper-rendered/v1_configmap_gatekeeper-dashboard.yaml":                                                                          configGatekeeperRenderedV1_configmap_gatekeeperDashboardYaml,
 	"config/gatekeeper-rendered/v1_namespace_gatekeeper-system.yaml":                                                                             configGatekeeperRenderedV1_namespace_gatekeeperSystemYaml,
 	"config/gatekeeper-rendered/v1_resourcequota_gatekeeper-critical-pods.yaml":                                                                  configGatekeeperRenderedV1_resourcequota_gatekeeperCriticalPodsYaml,
 	"config/gatekeeper-rendered/v1_secret_gatekeeper-webhook-server-cert.yaml":                                                                   configGatekeeperRenderedV1_secret_gatekeeperWebhookServerCertYaml,
+	"config/gatekeeper-rendered/v1_service_gatekeeper-metrics-service.yaml":                                                                      configGatekeeperRenderedV1_service_gatekeeperMetricsServiceYaml,
 	"config/gatekeeper-rendered/v1_service_gatekeeper-webhook-service.yaml":                                                                      configGatekeeperRenderedV1_service_gatekeeperWebhookServiceYaml,
 	"config/gatekeeper-rendered/v1_serviceaccount_gatekeeper-admin.yaml":                                                                         configGatekeeperRenderedV1_serviceaccount_gatekeeperAdminYaml,
 }
@@ -5052,14 +5589,20 @@ var _bintree = &bintree{nil, map[string]*bintree{
 			"apiextensions.k8s.io_v1_customresourcedefinition_providers.externaldata.gatekeeper.sh.yaml":                      {configGatekeeperRenderedApiextensionsK8sIo_v1_customresourcedefinition_providersExternaldataGatekeeperShYaml, map[string]*bintree{}},
 			"apps_v1_deployment_gatekeeper-audit.yaml":                                                                        {configGatekeeperRenderedApps_v1_deployment_gatekeeperAuditYaml, map[string]*bintree{}},
 			"apps_v1_deployment_gatekeeper-controller-manager.yaml":                                                           {configGatekeeperRenderedApps_v1_deployment_gatekeeperControllerManagerYaml, map[string]*bintree{}},
+			"monitoring.coreos.com_v1_prometheusrule_gatekeeper-alerts.yaml":                                                  {configGatekeeperRenderedMonitoringCoreosCom_v1_prometheusrule_gatekeeperAlertsYaml, map[string]*bintree{}},
+			"monitoring.coreos.com_v1_servicemonitor_gatekeeper-metrics.yaml":                                                 {configGatekeeperRenderedMonitoringCoreosCom_v1_servicemonitor_gatekeeperMetricsYaml, map[string]*bintree{}},
 			"policy_v1_poddisruptionbudget_gatekeeper-controller-manager.yaml":                                                {configGatekeeperRenderedPolicy_v1_poddisruptionbudget_gatekeeperControllerManagerYaml, map[string]*bintree{}},
 			"rbac.authorization.k8s.io_v1_clusterrole_gatekeeper-manager-role.yaml":                                           {configGatekeeperRenderedRbacAuthorizationK8sIo_v1_clusterrole_gatekeeperManagerRoleYaml, map[string]*bintree{}},
 			"rbac.authorization.k8s.io_v1_clusterrolebinding_gatekeeper-manager-rolebinding.yaml":                             {configGatekeeperRenderedRbacAuthorizationK8sIo_v1_clusterrolebinding_gatekeeperManagerRolebindingYaml, map[string]*bintree{}},
 			"rbac.authorization.k8s.io_v1_role_gatekeeper-manager-role.yaml":                                                  {configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperManagerRoleYaml, map[string]*bintree{}},
+			"rbac.authorization.k8s.io_v1_role_gatekeeper-prometheus-k8s.yaml":                                                {configGatekeeperRenderedRbacAuthorizationK8sIo_v1_role_gatekeeperPrometheusK8sYaml, map[string]*bintree{}},
 			"rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-manager-rolebinding.yaml":                                    {configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperManagerRolebindingYaml, map[string]*bintree{}},
+			"rbac.authorization.k8s.io_v1_rolebinding_gatekeeper-prometheus-k8s.yaml":                                         {configGatekeeperRenderedRbacAuthorizationK8sIo_v1_rolebinding_gatekeeperPrometheusK8sYaml, map[string]*bintree{}},
+			"v1_configmap_gatekeeper-dashboard.yaml":                                                                          {configGatekeeperRenderedV1_configmap_gatekeeperDashboardYaml, map[string]*bintree{}},
 			"v1_namespace_gatekeeper-system.yaml":                                                                             {configGatekeeperRenderedV1_namespace_gatekeeperSystemYaml, map[string]*bintree{}},
 			"v1_resourcequota_gatekeeper-critical-pods.yaml":                                                                  {configGatekeeperRenderedV1_resourcequota_gatekeeperCriticalPodsYaml, map[string]*bintree{}},
 			"v1_secret_gatekeeper-webhook-server-cert.yaml":                                                                   {configGatekeeperRenderedV1_secret_gatekeeperWebhookServerCertYaml, map[string]*bintree{}},
+			"v1_service_gatekeeper-metrics-service.yaml":                                                                      {configGatekeeperRenderedV1_service_gatekeeperMetricsServiceYaml, map[string]*bintree{}},
 			"v1_service_gatekeeper-webhook-service.yaml":                                                                      {configGatekeeperRenderedV1_service_gatekeeperWebhookServiceYaml, map[string]*bintree{}},
 			"v1_serviceaccount_gatekeeper-admin.yaml":                                                                         {configGatekeeperRenderedV1_serviceaccount_gatekeeperAdminYaml, map[string]*bintree{}},
 		}},
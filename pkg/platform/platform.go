@@ -92,11 +92,13 @@ func (pv k8SBasedPlatformVersioner) getPlatformInfo(client discovery.DiscoveryIn
 	}
 
 	for _, v := range apiList.Groups {
-		if v.Name == "route.openshift.io" {
-
+		switch v.Name {
+		case "route.openshift.io":
 			log.Info("route.openshift.io found in apis, platform is OpenShift")
 			info.Name = OpenShift
-			break
+		case "monitoring.coreos.com":
+			log.Info("monitoring.coreos.com found in apis, prometheus-operator is available")
+			info.PrometheusOperator = true
 		}
 	}
 	log.Info(info.String())
@@ -27,15 +27,23 @@ type PlatformInfo struct {
 	Name       PlatformType `json:"name"`
 	K8SVersion string       `json:"k8sVersion"`
 	OS         string       `json:"os"`
+	// PrometheusOperator is true if the monitoring.coreos.com API group of
+	// the prometheus-operator is available.
+	PrometheusOperator bool `json:"prometheusOperator"`
 }
 
 func (info PlatformInfo) IsOpenShift() bool {
 	return info.Name == OpenShift
 }
 
+func (info PlatformInfo) HasPrometheusOperator() bool {
+	return info.PrometheusOperator
+}
+
 func (info PlatformInfo) String() string {
 	return "PlatformInfo [" +
 		"Name: " + fmt.Sprintf("%v", info.Name) +
 		", K8SVersion: " + info.K8SVersion +
-		", OS: " + info.OS + "]"
+		", OS: " + info.OS +
+		", PrometheusOperator: " + fmt.Sprintf("%t", info.PrometheusOperator) + "]"
 }
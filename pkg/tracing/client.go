@@ -0,0 +1,148 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WrapClient returns a client that records a span for each API call made
+// with a context carrying a span.
+func WrapClient(c client.Client) client.Client {
+	return &tracingClient{Client: c}
+}
+
+type tracingClient struct {
+	client.Client
+}
+
+func (c *tracingClient) startSpan(ctx context.Context, verb string, obj client.Object) (context.Context, func(error)) {
+	attrs := []attribute.KeyValue{
+		attribute.String("k8s.verb", verb),
+		attribute.String("k8s.namespace", obj.GetNamespace()),
+		attribute.String("k8s.name", obj.GetName()),
+	}
+	if gvk := obj.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		attrs = append(attrs, attribute.String("k8s.kind", gvk.Kind))
+	}
+	ctx, span := StartSpan(ctx, "k8s."+verb, attrs...)
+	return ctx, func(err error) { EndSpan(span, err) }
+}
+
+func (c *tracingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, span := StartSpan(ctx, "k8s.get",
+		attribute.String("k8s.verb", "get"),
+		attribute.String("k8s.namespace", key.Namespace),
+		attribute.String("k8s.name", key.Name),
+		attribute.String("k8s.kind", obj.GetObjectKind().GroupVersionKind().Kind),
+	)
+	err := c.Client.Get(ctx, key, obj, opts...)
+	EndSpan(span, client.IgnoreNotFound(err))
+	return err
+}
+
+func (c *tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, end := c.startSpan(ctx, "create", obj)
+	err := c.Client.Create(ctx, obj, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, end := c.startSpan(ctx, "update", obj)
+	err := c.Client.Update(ctx, obj, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, end := c.startSpan(ctx, "patch", obj)
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, end := c.startSpan(ctx, "delete", obj)
+	err := c.Client.Delete(ctx, obj, opts...)
+	end(err)
+	return err
+}
+
+func (c *tracingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	ctx, span := StartSpan(ctx, "k8s.list",
+		attribute.String("k8s.verb", "list"),
+		attribute.String("k8s.namespace", listOpts.Namespace),
+		attribute.String("k8s.kind", strings.TrimSuffix(list.GetObjectKind().GroupVersionKind().Kind, "List")),
+	)
+	err := c.Client.List(ctx, list, opts...)
+	EndSpan(span, err)
+	return err
+}
+
+func (c *tracingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	deleteAllOfOpts := (&client.DeleteAllOfOptions{}).ApplyOptions(opts)
+	ctx, span := StartSpan(ctx, "k8s.deletecollection",
+		attribute.String("k8s.verb", "deletecollection"),
+		attribute.String("k8s.namespace", deleteAllOfOpts.Namespace),
+		attribute.String("k8s.kind", obj.GetObjectKind().GroupVersionKind().Kind),
+	)
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	EndSpan(span, err)
+	return err
+}
+
+func (c *tracingClient) Status() client.SubResourceWriter {
+	return &tracingSubResourceWriter{SubResourceWriter: c.Client.Status(), client: c, subResource: "status"}
+}
+
+// tracingSubResourceWriter records a span for each write of a subresource.
+type tracingSubResourceWriter struct {
+	client.SubResourceWriter
+	client      *tracingClient
+	subResource string
+}
+
+func (w *tracingSubResourceWriter) startSpan(ctx context.Context, verb string, obj client.Object) (context.Context, func(error)) {
+	return w.client.startSpan(ctx, verb+"/"+w.subResource, obj)
+}
+
+func (w *tracingSubResourceWriter) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	ctx, end := w.startSpan(ctx, "create", obj)
+	err := w.SubResourceWriter.Create(ctx, obj, subResource, opts...)
+	end(err)
+	return err
+}
+
+func (w *tracingSubResourceWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	ctx, end := w.startSpan(ctx, "update", obj)
+	err := w.SubResourceWriter.Update(ctx, obj, opts...)
+	end(err)
+	return err
+}
+
+func (w *tracingSubResourceWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	ctx, end := w.startSpan(ctx, "patch", obj)
+	err := w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+	end(err)
+	return err
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTracingClient(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer func() { _ = provider.Shutdown(ctx) }()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "gatekeeper-system", Name: "gatekeeper-audit"}}
+	c := WrapClient(fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build())
+
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), pod)).To(Succeed())
+	g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "gatekeeper-system", Name: "missing"}, &corev1.Pod{})).ToNot(Succeed())
+	g.Expect(c.List(ctx, &corev1.PodList{}, client.InNamespace("gatekeeper-system"))).To(Succeed())
+	pod.Status.Phase = corev1.PodRunning
+	g.Expect(c.Status().Update(ctx, pod)).To(Succeed())
+	g.Expect(c.Status().Patch(ctx, pod, client.MergeFrom(pod.DeepCopy()))).To(Succeed())
+	g.Expect(c.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("gatekeeper-system"))).To(Succeed())
+
+	spans := recorder.Ended()
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	g.Expect(names).To(Equal([]string{
+		"k8s.get", "k8s.get", "k8s.list", "k8s.update/status", "k8s.patch/status", "k8s.deletecollection",
+	}))
+
+	// NotFound is not recorded as an error of the span.
+	g.Expect(spans[1].Status().Code).To(Equal(codes.Unset))
+	g.Expect(spans[2].Attributes()).To(ContainElement(attribute.String("k8s.namespace", "gatekeeper-system")))
+	g.Expect(spans[3].Attributes()).To(ContainElement(attribute.String("k8s.name", "gatekeeper-audit")))
+	g.Expect(spans[5].Attributes()).To(ContainElement(attribute.String("k8s.namespace", "gatekeeper-system")))
+}